@@ -1,11 +1,47 @@
 package awscfn
 
 import (
+	"strings"
+
 	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
 )
 
 type awscfnConfig struct {
-	Paths []string `hcl:"paths,optional" steampipe:"watch"`
+	Paths                []string          `hcl:"paths,optional" steampipe:"watch"`
+	SkipInvalidTemplates *bool             `hcl:"skip_invalid_templates,optional"`
+	SkipNonTemplates     *bool             `hcl:"skip_non_templates,optional"`
+	ParameterValues      map[string]string `hcl:"parameter_values,optional"`
+	ParameterFiles       []string          `hcl:"parameter_files,optional"`
+	PseudoParameters     *pseudoParameters `hcl:"pseudo_parameters,block"`
+	Profiles             []profileConfig   `hcl:"profile,block"`
+	ParameterFilePaths   []string          `hcl:"parameter_file_paths,optional" steampipe:"watch"`
+	SSMParameterValues   map[string]string `hcl:"ssm_parameter_values,optional"`
+	ExpandSAMResources   *bool             `hcl:"expand_sam_resources,optional"`
+}
+
+// profileConfig is a named set of values used to evaluate templates, which
+// are applied on top of the connection values when selected using the profile
+// column, e.g. to compare environments
+type profileConfig struct {
+	Name               string            `hcl:"name,label"`
+	ParameterValues    map[string]string `hcl:"parameter_values,optional"`
+	ParameterFiles     []string          `hcl:"parameter_files,optional"`
+	PseudoParameters   *pseudoParameters `hcl:"pseudo_parameters,block"`
+	SSMParameterValues map[string]string `hcl:"ssm_parameter_values,optional"`
+}
+
+// pseudoParameters are the values of the AWS pseudo parameters, e.g.
+// AWS::Region, used to evaluate templates. AvailabilityZones are the zones of
+// the region returned by Fn::GetAZs, which differ between accounts.
+type pseudoParameters struct {
+	Region            *string  `hcl:"region,optional"`
+	AccountID         *string  `hcl:"account_id,optional"`
+	Partition         *string  `hcl:"partition,optional"`
+	StackName         *string  `hcl:"stack_name,optional"`
+	StackID           *string  `hcl:"stack_id,optional"`
+	URLSuffix         *string  `hcl:"url_suffix,optional"`
+	NotificationARNs  []string `hcl:"notification_arns,optional"`
+	AvailabilityZones []string `hcl:"availability_zones,optional"`
 }
 
 func ConfigInstance() interface{} {
@@ -20,3 +56,75 @@ func GetConfig(connection *plugin.Connection) awscfnConfig {
 	config, _ := connection.Config.(awscfnConfig)
 	return config
 }
+
+// profile returns the named profile, or nil if it is not defined
+func (c awscfnConfig) profile(name string) *profileConfig {
+	for i := range c.Profiles {
+		if c.Profiles[i].Name == name {
+			return &c.Profiles[i]
+		}
+	}
+	return nil
+}
+
+// values returns the configured pseudo parameter values keyed by name. The
+// partition and URL suffix are derived from the region if not configured.
+func (p *pseudoParameters) values() map[string]interface{} {
+	values := map[string]interface{}{}
+	if p == nil {
+		return values
+	}
+
+	if p.Region != nil {
+		values["AWS::Region"] = *p.Region
+		values["AWS::Partition"], values["AWS::URLSuffix"] = regionPartition(*p.Region)
+	}
+	if p.Partition != nil {
+		values["AWS::Partition"] = *p.Partition
+	}
+	if p.URLSuffix != nil {
+		values["AWS::URLSuffix"] = *p.URLSuffix
+	}
+	if p.AccountID != nil {
+		values["AWS::AccountId"] = *p.AccountID
+	}
+	if p.StackName != nil {
+		values["AWS::StackName"] = *p.StackName
+	}
+	if p.StackID != nil {
+		values["AWS::StackId"] = *p.StackID
+	}
+	if p.NotificationARNs != nil {
+		arns := []interface{}{}
+		for _, arn := range p.NotificationARNs {
+			arns = append(arns, arn)
+		}
+		values["AWS::NotificationARNs"] = arns
+	}
+
+	return values
+}
+
+// availabilityZones returns the configured availability zones, or nil if they
+// are not configured
+func (p *pseudoParameters) availabilityZones() []string {
+	if p == nil {
+		return nil
+	}
+	return p.AvailabilityZones
+}
+
+// regionPartition returns the partition and URL suffix of the region
+func regionPartition(region string) (string, string) {
+	switch {
+	case strings.HasPrefix(region, "cn-"):
+		return "aws-cn", "amazonaws.com.cn"
+	case strings.HasPrefix(region, "us-gov-"):
+		return "aws-us-gov", "amazonaws.com"
+	case strings.HasPrefix(region, "us-isob-"):
+		return "aws-iso-b", "sc2s.sgov.gov"
+	case strings.HasPrefix(region, "us-iso-"):
+		return "aws-iso", "c2s.ic.gov"
+	}
+	return "aws", "amazonaws.com"
+}
@@ -0,0 +1,178 @@
+package awscfn
+
+// resourceDependency is a dependency of one resource on another, either
+// explicit through the DependsOn attribute, or implicit through a Ref,
+// Fn::GetAtt or Fn::Sub reference to the other resource
+type resourceDependency struct {
+	ResourceName  string
+	DependsOnName string
+	// The functions or attributes that make the dependency, in the order they
+	// first appear, e.g. ["DependsOn", "Fn::GetAtt"]
+	Functions  []string
+	IsExplicit bool
+	IsImplicit bool
+	// True if the dependency is part of a circular dependency, which
+	// CloudFormation rejects
+	InCycle bool
+	// The creation order of each resource, starting from 0 for resources
+	// without dependencies. Resources can be created in parallel with others
+	// of the same order. Nil if the resource is in, or depends on, a cycle.
+	CreationOrder          *int
+	DependsOnCreationOrder *int
+	// The line of the first reference that makes the dependency
+	Line int
+}
+
+// dependencies returns the dependencies between the resources of the
+// template, ordered by the position of their first reference in the file.
+// References to resources that are not declared are ignored.
+func (t *cfnTemplate) dependencies() []resourceDependency {
+	var dependencies []resourceDependency
+	index := map[[2]string]int{}
+	graph := map[string][]string{}
+
+	for _, r := range t.references() {
+		if r.SourceSection != "Resources" || r.TargetSection != "Resources" {
+			continue
+		}
+		// Resources generated by the SAM transform are not part of the graph
+		if _, ok := t.Resources[r.TargetName]; !ok {
+			continue
+		}
+		key := [2]string{r.SourceName, r.TargetName}
+		i, ok := index[key]
+		if !ok {
+			i = len(dependencies)
+			index[key] = i
+			dependencies = append(dependencies, resourceDependency{
+				ResourceName:  r.SourceName,
+				DependsOnName: r.TargetName,
+				Line:          r.Line,
+			})
+			graph[r.SourceName] = append(graph[r.SourceName], r.TargetName)
+		}
+		d := &dependencies[i]
+		if !containsString(d.Functions, r.Function) {
+			d.Functions = append(d.Functions, r.Function)
+		}
+		if r.Function == "DependsOn" {
+			d.IsExplicit = true
+		} else {
+			d.IsImplicit = true
+		}
+	}
+
+	components := stronglyConnectedComponents(sortedKeys(t.Resources), graph)
+	componentSize := map[int]int{}
+	for _, c := range components {
+		componentSize[c]++
+	}
+	inCycle := func(from, to string) bool {
+		return components[from] == components[to] && (from == to || componentSize[components[from]] > 1)
+	}
+
+	cyclic := map[string]bool{}
+	for _, d := range dependencies {
+		if inCycle(d.ResourceName, d.DependsOnName) {
+			cyclic[d.ResourceName] = true
+		}
+	}
+	orders := creationOrders(graph, cyclic)
+
+	for i := range dependencies {
+		d := &dependencies[i]
+		d.InCycle = inCycle(d.ResourceName, d.DependsOnName)
+		d.CreationOrder = orders[d.ResourceName]
+		d.DependsOnCreationOrder = orders[d.DependsOnName]
+	}
+
+	return dependencies
+}
+
+// stronglyConnectedComponents returns the strongly connected component of
+// each node of the graph, using Tarjan's algorithm. Nodes in the same
+// component are part of a cycle if the component has more than one node.
+func stronglyConnectedComponents(nodes []string, graph map[string][]string) map[string]int {
+	components := map[string]int{}
+	indexes := map[string]int{}
+	lowLinks := map[string]int{}
+	onStack := map[string]bool{}
+	var stack []string
+	next, component := 0, 0
+
+	var connect func(node string)
+	connect = func(node string) {
+		indexes[node], lowLinks[node] = next, next
+		next++
+		stack = append(stack, node)
+		onStack[node] = true
+
+		for _, target := range graph[node] {
+			if _, visited := indexes[target]; !visited {
+				connect(target)
+				lowLinks[node] = min(lowLinks[node], lowLinks[target])
+			} else if onStack[target] {
+				lowLinks[node] = min(lowLinks[node], indexes[target])
+			}
+		}
+
+		if lowLinks[node] != indexes[node] {
+			return
+		}
+		for {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[top] = false
+			components[top] = component
+			if top == node {
+				break
+			}
+		}
+		component++
+	}
+
+	for _, node := range nodes {
+		if _, visited := indexes[node]; !visited {
+			connect(node)
+		}
+	}
+	return components
+}
+
+// creationOrders returns the creation order of each resource in the graph,
+// i.e. one more than the highest order of the resources it depends on. The
+// order of resources that are in, or depend on, a cycle is nil.
+func creationOrders(graph map[string][]string, cyclic map[string]bool) map[string]*int {
+	orders := map[string]*int{}
+	resolved := map[string]bool{}
+
+	var order func(node string) *int
+	order = func(node string) *int {
+		if resolved[node] {
+			return orders[node]
+		}
+		resolved[node] = true
+		if cyclic[node] {
+			return nil
+		}
+
+		n := 0
+		for _, target := range graph[node] {
+			targetOrder := order(target)
+			if targetOrder == nil {
+				return nil
+			}
+			n = max(n, *targetOrder+1)
+		}
+		orders[node] = &n
+		return orders[node]
+	}
+
+	for node, targets := range graph {
+		order(node)
+		for _, target := range targets {
+			order(target)
+		}
+	}
+	return orders
+}
@@ -0,0 +1,736 @@
+package awscfn
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+)
+
+// templateEvaluator evaluates CloudFormation intrinsic functions against the
+// values declared in a template. Functions that cannot be evaluated offline,
+// e.g. Fn::GetAtt or a Ref to a resource, are left intact.
+type templateEvaluator struct {
+	parameters map[string]interface{}
+	mappings   map[string]interface{}
+	conditions map[string]interface{}
+
+	// Stand-in values of SSM parameters, used to resolve SSM parameter types
+	// and dynamic references
+	ssmParameters map[string]string
+
+	// Availability zones of the AWS::Region pseudo parameter, returned by
+	// Fn::GetAZs
+	availabilityZones []string
+
+	// Cache of evaluated conditions, and the set of conditions currently
+	// being evaluated to guard against circular condition references
+	resolvedConditions  map[string]bool
+	resolvingConditions map[string]bool
+
+	// Number of nested Fn::FindInMap lookups, to guard against mapping values
+	// that refer back to themselves
+	mappingDepth int
+}
+
+// maxMappingDepth is the maximum number of nested Fn::FindInMap lookups
+const maxMappingDepth = 16
+
+// noValueType is the type of the AWS::NoValue pseudo parameter, which removes
+// the property or list item it is assigned to
+type noValueType struct{}
+
+var noValue = noValueType{}
+
+// MarshalJSON returns AWS::NoValue as a Ref, for the rare cases it is used as
+// the argument of a function that cannot be evaluated
+func (noValueType) MarshalJSON() ([]byte, error) {
+	return []byte(`{"Ref":"AWS::NoValue"}`), nil
+}
+
+// evaluationValues are the values configured for a connection, or one of its
+// profiles, that are used to evaluate templates
+type evaluationValues struct {
+	// Name of the profile, empty for the connection values
+	Profile string
+	// Parameter values that take precedence over parameter defaults
+	Parameters map[string]string
+	// Pseudo parameter values, keyed by name, e.g. AWS::Region
+	PseudoParameters map[string]interface{}
+	// Stand-in values of SSM parameters, keyed by SSM parameter name
+	SSMParameters map[string]string
+	// Availability zones of the configured region, used by Fn::GetAZs
+	AvailabilityZones []string
+}
+
+// getEvaluationValues returns the values for each profile requested through
+// the profile qual, or the connection values if no profile was requested
+func getEvaluationValues(ctx context.Context, d *plugin.QueryData) ([]*evaluationValues, error) {
+	awscfnConfig := GetConfig(d.Connection)
+
+	base := &evaluationValues{
+		Parameters:        map[string]string{},
+		PseudoParameters:  awscfnConfig.PseudoParameters.values(),
+		SSMParameters:     map[string]string{},
+		AvailabilityZones: awscfnConfig.PseudoParameters.availabilityZones(),
+	}
+	for k, value := range awscfnConfig.SSMParameterValues {
+		base.SSMParameters[k] = value
+	}
+	err := readParameterValues(ctx, base.Parameters, awscfnConfig.ParameterFiles, awscfnConfig.ParameterValues)
+	if err != nil {
+		return nil, err
+	}
+
+	// The qual is a list if the query uses "profile in (...)" and the list
+	// could not be split into separate list calls
+	var names []string
+	if qual := d.EqualsQuals["profile"]; qual != nil {
+		if list := qual.GetListValue(); list != nil {
+			for _, v := range list.Values {
+				names = append(names, v.GetStringValue())
+			}
+		} else {
+			names = []string{qual.GetStringValue()}
+		}
+	}
+	if len(names) == 0 {
+		return []*evaluationValues{base}, nil
+	}
+
+	var values []*evaluationValues
+	for _, name := range names {
+		profile := awscfnConfig.profile(name)
+		if profile == nil {
+			return nil, fmt.Errorf("profile %q is not defined in the connection config", name)
+		}
+
+		// Profile values are applied on top of the connection values
+		v := &evaluationValues{
+			Profile:          name,
+			Parameters:       map[string]string{},
+			PseudoParameters: map[string]interface{}{},
+			SSMParameters:    map[string]string{},
+		}
+		for k, value := range base.Parameters {
+			v.Parameters[k] = value
+		}
+		err := readParameterValues(ctx, v.Parameters, profile.ParameterFiles, profile.ParameterValues)
+		if err != nil {
+			return nil, err
+		}
+		for k, value := range base.PseudoParameters {
+			v.PseudoParameters[k] = value
+		}
+		for k, value := range profile.PseudoParameters.values() {
+			v.PseudoParameters[k] = value
+		}
+		// The availability zones of the connection only apply to its region
+		v.AvailabilityZones = base.AvailabilityZones
+		if profile.PseudoParameters != nil && (profile.PseudoParameters.Region != nil || profile.PseudoParameters.AvailabilityZones != nil) {
+			v.AvailabilityZones = profile.PseudoParameters.AvailabilityZones
+		}
+		for k, value := range base.SSMParameters {
+			v.SSMParameters[k] = value
+		}
+		for k, value := range profile.SSMParameterValues {
+			v.SSMParameters[k] = value
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+// newTemplateEvaluator returns an evaluator for the template. Parameters are
+// resolved using the given values, falling back to their default values.
+func newTemplateEvaluator(template *cfnTemplate, values *evaluationValues) *templateEvaluator {
+	e := &templateEvaluator{
+		parameters:          map[string]interface{}{},
+		mappings:            template.Mappings,
+		conditions:          template.Conditions,
+		ssmParameters:       values.SSMParameters,
+		availabilityZones:   values.AvailabilityZones,
+		resolvedConditions:  map[string]bool{},
+		resolvingConditions: map[string]bool{},
+	}
+
+	// Pseudo parameters are referenced in the same way as parameters, i.e.
+	// using Ref or Fn::Sub
+	e.parameters["AWS::NoValue"] = noValue
+	for name, value := range values.PseudoParameters {
+		e.parameters[name] = value
+	}
+
+	for name, v := range template.Parameters {
+		data, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		typeName, _ := data["Type"].(string)
+
+		var value interface{}
+		if v, ok := values.Parameters[name]; ok {
+			value = parameterValue(typeName, v)
+		} else if data["Default"] != nil {
+			value = parameterValue(typeName, data["Default"])
+		} else {
+			continue
+		}
+
+		// The value of an SSM parameter type is the name of the SSM
+		// parameter, which can only be resolved using the configured values
+		if t := parseParameterType(typeName); t.SSMValueType != "" {
+			ssmValue, ok := values.SSMParameters[scalarString(value)]
+			if !ok {
+				continue
+			}
+			value = parameterValue(t.SSMValueType, ssmValue)
+		}
+		e.parameters[name] = value
+	}
+
+	return e
+}
+
+// parameterValue returns the value of a parameter as returned by Ref, i.e. a
+// list for list parameter types and a string otherwise. The value of an SSM
+// parameter type is the name of the SSM parameter, which is a string.
+func parameterValue(typeName string, value interface{}) interface{} {
+	t := parseParameterType(typeName)
+	isList := t.IsList && !t.IsSSM
+
+	if list, ok := value.([]interface{}); ok {
+		if isList {
+			return list
+		}
+		var items []string
+		for _, item := range list {
+			items = append(items, scalarString(item))
+		}
+		return strings.Join(items, ",")
+	}
+	if !isScalar(value) {
+		return value
+	}
+
+	s := scalarString(value)
+	if !isList {
+		return s
+	}
+	var items []interface{}
+	for _, item := range strings.Split(s, ",") {
+		items = append(items, strings.TrimSpace(item))
+	}
+	return items
+}
+
+// evaluate returns the value with the intrinsic functions it contains
+// evaluated where possible. Functions that cannot be evaluated are returned
+// with their arguments evaluated. Nil is returned if the value resolves to
+// AWS::NoValue.
+func (e *templateEvaluator) evaluate(v interface{}) interface{} {
+	result := e.evaluateValue(v)
+	if result == noValue {
+		return nil
+	}
+	return result
+}
+
+func (e *templateEvaluator) evaluateValue(v interface{}) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		if fn, args, ok := intrinsicFunction(value); ok {
+			return e.evaluateFunction(fn, args)
+		}
+		// Properties that resolve to AWS::NoValue are removed
+		result := make(map[string]interface{}, len(value))
+		for k, item := range value {
+			if itemValue := e.evaluateValue(item); itemValue != noValue {
+				result[k] = itemValue
+			}
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, 0, len(value))
+		for _, item := range value {
+			if itemValue := e.evaluateValue(item); itemValue != noValue {
+				result = append(result, itemValue)
+			}
+		}
+		return result
+	case string:
+		return e.resolveDynamicReferences(value)
+	}
+	return v
+}
+
+// evaluateFunction evaluates a single intrinsic function call
+func (e *templateEvaluator) evaluateFunction(fn string, args interface{}) interface{} {
+	switch fn {
+	case "Fn::If":
+		// Only the selected branch is evaluated
+		if list, ok := args.([]interface{}); ok && len(list) == 3 {
+			if name, ok := list[0].(string); ok {
+				if result, ok := e.evaluateCondition(name); ok {
+					if result {
+						return e.evaluateValue(list[1])
+					}
+					return e.evaluateValue(list[2])
+				}
+			}
+		}
+	case "Fn::Sub":
+		result := e.evaluateSub(args)
+		if s, ok := result.(string); ok {
+			return e.resolveDynamicReferences(s)
+		}
+		return result
+	}
+
+	evaluated := e.evaluateValue(args)
+	if result, ok := e.applyFunction(fn, evaluated); ok {
+		// Functions such as Fn::Join may build a dynamic reference
+		if s, ok := result.(string); ok {
+			return e.resolveDynamicReferences(s)
+		}
+		return result
+	}
+	return map[string]interface{}{fn: evaluated}
+}
+
+// applyFunction applies the function to its evaluated arguments, and returns
+// false if the result cannot be determined
+func (e *templateEvaluator) applyFunction(fn string, args interface{}) (interface{}, bool) {
+	list, isList := args.([]interface{})
+
+	switch fn {
+	case "Ref":
+		value, ok := e.parameters[args.(string)]
+		return value, ok
+
+	case "Condition", "Fn::Equals", "Fn::And", "Fn::Or", "Fn::Not":
+		return e.evaluateConditionExpression(map[string]interface{}{fn: args})
+
+	case "Fn::Base64":
+		s, ok := args.(string)
+		if !ok {
+			return nil, false
+		}
+		return base64.StdEncoding.EncodeToString([]byte(s)), true
+
+	case "Fn::Join":
+		if !isList || len(list) != 2 {
+			return nil, false
+		}
+		delimiter, ok := list[0].(string)
+		if !ok {
+			return nil, false
+		}
+		items, ok := list[1].([]interface{})
+		if !ok {
+			return nil, false
+		}
+		var values []string
+		for _, item := range items {
+			if !isScalar(item) {
+				return nil, false
+			}
+			values = append(values, scalarString(item))
+		}
+		return strings.Join(values, delimiter), true
+
+	case "Fn::Select":
+		if !isList || len(list) != 2 || !isScalar(list[0]) {
+			return nil, false
+		}
+		index, err := strconv.Atoi(scalarString(list[0]))
+		if err != nil {
+			return nil, false
+		}
+		items, ok := list[1].([]interface{})
+		if !ok || index < 0 || index >= len(items) {
+			return nil, false
+		}
+		return items[index], true
+
+	case "Fn::Split":
+		if !isList || len(list) != 2 {
+			return nil, false
+		}
+		delimiter, ok := list[0].(string)
+		if !ok || delimiter == "" {
+			return nil, false
+		}
+		s, ok := list[1].(string)
+		if !ok {
+			return nil, false
+		}
+		var items []interface{}
+		for _, item := range strings.Split(s, delimiter) {
+			items = append(items, item)
+		}
+		return items, true
+
+	case "Fn::FindInMap":
+		if !isList || len(list) < 3 {
+			return nil, false
+		}
+		var keys []string
+		for _, item := range list[:3] {
+			if !isScalar(item) {
+				return nil, false
+			}
+			keys = append(keys, scalarString(item))
+		}
+		if value, ok := e.findInMap(keys[0], keys[1], keys[2]); ok {
+			if e.mappingDepth >= maxMappingDepth {
+				return nil, false
+			}
+			e.mappingDepth++
+			defer func() { e.mappingDepth-- }()
+			return e.evaluateValue(value), true
+		}
+		// The AWS::LanguageExtensions transform allows a default value
+		if len(list) == 4 {
+			if options, ok := list[3].(map[string]interface{}); ok && options["DefaultValue"] != nil {
+				return options["DefaultValue"], true
+			}
+		}
+		return nil, false
+
+	case "Fn::GetAZs":
+		region, ok := args.(string)
+		if !ok || e.availabilityZones == nil {
+			return nil, false
+		}
+		// An empty string is the region the stack is created in. The zones
+		// are only known for the configured region.
+		if configured, _ := e.parameters["AWS::Region"].(string); region != "" && region != configured {
+			return nil, false
+		}
+		zones := make([]interface{}, len(e.availabilityZones))
+		for i, zone := range e.availabilityZones {
+			zones[i] = zone
+		}
+		return zones, true
+
+	case "Fn::Cidr":
+		if !isList || len(list) != 3 || !isScalar(list[1]) || !isScalar(list[2]) {
+			return nil, false
+		}
+		ipBlock, ok := list[0].(string)
+		if !ok {
+			return nil, false
+		}
+		count, err := strconv.Atoi(scalarString(list[1]))
+		if err != nil {
+			return nil, false
+		}
+		cidrBits, err := strconv.Atoi(scalarString(list[2]))
+		if err != nil {
+			return nil, false
+		}
+		return cidrSubnets(ipBlock, count, cidrBits)
+
+	case "Fn::Length":
+		if !isList {
+			return nil, false
+		}
+		return len(list), true
+
+	case "Fn::ToJsonString":
+		if containsIntrinsicFunction(args) {
+			return nil, false
+		}
+		b, err := json.Marshal(args)
+		if err != nil {
+			return nil, false
+		}
+		return string(b), true
+	}
+
+	// e.g. Fn::GetAtt, Fn::ImportValue and Fn::Transform can only be resolved
+	// by CloudFormation
+	return nil, false
+}
+
+var subPlaceholderRegex = regexp.MustCompile(`\$\{([^}]*)\}`)
+
+// evaluateSub substitutes the Fn::Sub placeholders that can be resolved. If any
+// placeholder cannot be resolved, e.g. ${MyBucket.Arn}, Fn::Sub is returned
+// with the remaining placeholders.
+func (e *templateEvaluator) evaluateSub(args interface{}) interface{} {
+	var s string
+	variables := map[string]interface{}{}
+	switch value := args.(type) {
+	case string:
+		s = value
+	case []interface{}:
+		if len(value) != 2 {
+			return map[string]interface{}{"Fn::Sub": e.evaluateValue(args)}
+		}
+		str, isString := value[0].(string)
+		if !isString {
+			return map[string]interface{}{"Fn::Sub": e.evaluateValue(args)}
+		}
+		vars, ok := e.evaluateValue(value[1]).(map[string]interface{})
+		if !ok {
+			return map[string]interface{}{"Fn::Sub": e.evaluateValue(args)}
+		}
+		s = str
+		variables = vars
+	default:
+		return map[string]interface{}{"Fn::Sub": e.evaluateValue(args)}
+	}
+
+	unresolved := map[string]interface{}{}
+	resolved := true
+	result := subPlaceholderRegex.ReplaceAllStringFunc(s, func(placeholder string) string {
+		name := placeholder[2 : len(placeholder)-1]
+		// ${!Literal} is left as is until all placeholders are resolved
+		if strings.HasPrefix(name, "!") {
+			return placeholder
+		}
+
+		value, ok := variables[name]
+		if !ok {
+			value, ok = e.parameters[name]
+		}
+		if ok && isScalar(value) {
+			return scalarString(value)
+		}
+
+		resolved = false
+		if v, ok := variables[name]; ok {
+			unresolved[name] = v
+		}
+		return placeholder
+	})
+
+	if resolved {
+		return subPlaceholderRegex.ReplaceAllStringFunc(result, func(placeholder string) string {
+			return "${" + strings.TrimPrefix(placeholder[2:len(placeholder)-1], "!") + "}"
+		})
+	}
+	if len(unresolved) > 0 {
+		return map[string]interface{}{"Fn::Sub": []interface{}{result, unresolved}}
+	}
+	return map[string]interface{}{"Fn::Sub": result}
+}
+
+var ssmDynamicReferenceRegex = regexp.MustCompile(`\{\{resolve:ssm:([a-zA-Z0-9_.\-/]+)(?::\d+)?\}\}`)
+
+// resolveDynamicReferences replaces the SSM dynamic references in the string,
+// e.g. {{resolve:ssm:/app/instance-type}}, that have a configured value. Secure
+// string and Secrets Manager references are never resolved.
+func (e *templateEvaluator) resolveDynamicReferences(s string) string {
+	if len(e.ssmParameters) == 0 || !strings.Contains(s, "{{resolve:ssm:") {
+		return s
+	}
+	return ssmDynamicReferenceRegex.ReplaceAllStringFunc(s, func(reference string) string {
+		name := ssmDynamicReferenceRegex.FindStringSubmatch(reference)[1]
+		if value, ok := e.ssmParameters[name]; ok {
+			return value
+		}
+		return reference
+	})
+}
+
+// evaluateCondition returns the value of the named condition, and false if the
+// condition is not declared or cannot be evaluated
+func (e *templateEvaluator) evaluateCondition(name string) (bool, bool) {
+	if result, ok := e.resolvedConditions[name]; ok {
+		return result, true
+	}
+	expr, ok := e.conditions[name]
+	if !ok || e.resolvingConditions[name] {
+		return false, false
+	}
+
+	e.resolvingConditions[name] = true
+	result, ok := e.evaluateConditionExpression(expr)
+	delete(e.resolvingConditions, name)
+	if ok {
+		e.resolvedConditions[name] = result
+	}
+	return result, ok
+}
+
+// evaluateConditionExpression evaluates a condition function, i.e. Fn::Equals,
+// Fn::And, Fn::Or, Fn::Not or a reference to another condition
+func (e *templateEvaluator) evaluateConditionExpression(expr interface{}) (bool, bool) {
+	fn, args, ok := intrinsicFunction(expr)
+	if !ok {
+		if b, ok := expr.(bool); ok {
+			return b, true
+		}
+		return false, false
+	}
+
+	switch fn {
+	case "Condition":
+		return e.evaluateCondition(args.(string))
+	case "Fn::Equals":
+		list, ok := args.([]interface{})
+		if !ok || len(list) != 2 {
+			return false, false
+		}
+		left := e.evaluateValue(list[0])
+		right := e.evaluateValue(list[1])
+		if !isScalar(left) || !isScalar(right) {
+			return false, false
+		}
+		return scalarString(left) == scalarString(right), true
+	case "Fn::Not":
+		list, ok := args.([]interface{})
+		if !ok || len(list) != 1 {
+			return false, false
+		}
+		result, ok := e.evaluateConditionExpression(list[0])
+		return !result, ok
+	case "Fn::And", "Fn::Or":
+		list, ok := args.([]interface{})
+		if !ok || len(list) == 0 {
+			return false, false
+		}
+		// Short circuit only on a decisive value, so that a condition which
+		// cannot be fully evaluated is still reported as unresolved
+		decisive := fn == "Fn::Or"
+		resolved := true
+		for _, item := range list {
+			result, ok := e.evaluateConditionExpression(item)
+			if !ok {
+				resolved = false
+				continue
+			}
+			if result == decisive {
+				return decisive, true
+			}
+		}
+		return !decisive, resolved
+	}
+
+	return false, false
+}
+
+// findInMap returns the value for the given keys in the Mappings section
+func (e *templateEvaluator) findInMap(mapName, topLevelKey, secondLevelKey string) (interface{}, bool) {
+	mapping, ok := e.mappings[mapName].(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	topLevel, ok := mapping[topLevelKey].(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	value, ok := topLevel[secondLevelKey]
+	return value, ok
+}
+
+// maxCidrCount is the maximum number of CIDR blocks Fn::Cidr can return
+const maxCidrCount = 256
+
+// cidrSubnets returns the first count CIDR blocks with cidrBits host bits
+// within the IP block, as returned by Fn::Cidr
+func cidrSubnets(ipBlock string, count int, cidrBits int) (interface{}, bool) {
+	if count < 1 || count > maxCidrCount {
+		return nil, false
+	}
+
+	_, network, err := net.ParseCIDR(ipBlock)
+	if err != nil {
+		return nil, false
+	}
+	ones, bits := network.Mask.Size()
+	prefix := bits - cidrBits
+	if cidrBits < 1 || prefix < ones {
+		return nil, false
+	}
+	if prefix-ones < 31 && count > 1<<(prefix-ones) {
+		return nil, false
+	}
+
+	start := new(big.Int).SetBytes(network.IP)
+	step := new(big.Int).Lsh(big.NewInt(1), uint(cidrBits))
+	var subnets []interface{}
+	for i := 0; i < count; i++ {
+		offset := new(big.Int).Mul(step, big.NewInt(int64(i)))
+		ip := new(big.Int).Add(start, offset).FillBytes(make([]byte, len(network.IP)))
+		subnets = append(subnets, fmt.Sprintf("%s/%d", net.IP(ip).String(), prefix))
+	}
+	return subnets, true
+}
+
+// intrinsicFunction returns the function name and arguments if the value is an
+// intrinsic function call, i.e. a map with a single Ref, Condition or Fn:: key
+func intrinsicFunction(v interface{}) (string, interface{}, bool) {
+	data, ok := v.(map[string]interface{})
+	if !ok || len(data) != 1 {
+		return "", nil, false
+	}
+	for k, args := range data {
+		// Ref and Condition are also used as property names, e.g. the
+		// Condition of an IAM policy statement, but only take a string when
+		// used as a function
+		if k == "Ref" || k == "Condition" {
+			_, ok := args.(string)
+			return k, args, ok
+		}
+		if strings.HasPrefix(k, "Fn::") {
+			return k, args, true
+		}
+	}
+	return "", nil, false
+}
+
+// containsIntrinsicFunction returns true if the value contains any intrinsic
+// function calls
+func containsIntrinsicFunction(v interface{}) bool {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		if _, _, ok := intrinsicFunction(value); ok {
+			return true
+		}
+		for _, item := range value {
+			if containsIntrinsicFunction(item) {
+				return true
+			}
+		}
+	case []interface{}:
+		for _, item := range value {
+			if containsIntrinsicFunction(item) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func isScalar(v interface{}) bool {
+	switch v.(type) {
+	case string, bool, int, int64, float64:
+		return true
+	}
+	return false
+}
+
+// scalarString returns the string representation of a scalar value, as used
+// by CloudFormation when a number or boolean is used as a string
+func scalarString(v interface{}) string {
+	switch value := v.(type) {
+	case string:
+		return value
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	case nil:
+		return ""
+	}
+	return fmt.Sprintf("%v", v)
+}
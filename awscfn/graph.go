@@ -0,0 +1,99 @@
+package awscfn
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// graphDOT renders the resource dependency graph of the template in the
+// Graphviz DOT language. Each resource is a node labelled with its logical ID
+// and type, and each dependency is an edge from the resource to the resource
+// it depends on, labelled with the functions that make it. Edges that are
+// only declared through DependsOn are dashed, and edges in a cycle are red.
+func (t *cfnTemplate) graphDOT() string {
+	var b strings.Builder
+	b.WriteString("digraph {\n")
+	b.WriteString("  node [shape=box];\n")
+	for _, name := range t.resourceNames() {
+		fmt.Fprintf(&b, "  %s [label=%s];\n", dotQuote(name), dotQuote(name+`\n`+t.resourceType(name)))
+	}
+	for _, d := range t.dependencies() {
+		attributes := []string{"label=" + dotQuote(strings.Join(d.Functions, ", "))}
+		if !d.IsImplicit {
+			attributes = append(attributes, "style=dashed")
+		}
+		if d.InCycle {
+			attributes = append(attributes, "color=red")
+		}
+		fmt.Fprintf(&b, "  %s -> %s [%s];\n", dotQuote(d.ResourceName), dotQuote(d.DependsOnName), strings.Join(attributes, ", "))
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// graphMermaid renders the resource dependency graph of the template as a
+// Mermaid flowchart, in the same way as graphDOT. Nodes are given generated
+// IDs, since a logical ID may be a Mermaid keyword, e.g. end.
+func (t *cfnTemplate) graphMermaid() string {
+	var b strings.Builder
+	b.WriteString("flowchart TD\n")
+	ids := map[string]string{}
+	for i, name := range t.resourceNames() {
+		ids[name] = fmt.Sprintf("r%d", i)
+		fmt.Fprintf(&b, "  %s[\"%s<br/>%s\"]\n", ids[name], mermaidEscape(name), mermaidEscape(t.resourceType(name)))
+	}
+	var cycleEdges []string
+	for i, d := range t.dependencies() {
+		arrow := "-->"
+		if !d.IsImplicit {
+			arrow = "-.->"
+		}
+		fmt.Fprintf(&b, "  %s %s|\"%s\"| %s\n", ids[d.ResourceName], arrow, mermaidEscape(strings.Join(d.Functions, ", ")), ids[d.DependsOnName])
+		if d.InCycle {
+			cycleEdges = append(cycleEdges, fmt.Sprint(i))
+		}
+	}
+	if len(cycleEdges) > 0 {
+		fmt.Fprintf(&b, "  linkStyle %s stroke:red\n", strings.Join(cycleEdges, ","))
+	}
+	return b.String()
+}
+
+// resourceNames returns the logical IDs of the resources in the order they
+// are declared in the file
+func (t *cfnTemplate) resourceNames() []string {
+	root := &t.Root
+	if root.Kind == yaml.DocumentNode && len(root.Content) > 0 {
+		root = root.Content[0]
+	}
+	resources := mappingValue(root, "Resources")
+	if resources == nil || resources.Kind != yaml.MappingNode {
+		return sortedKeys(t.Resources)
+	}
+
+	var names []string
+	for i := 0; i+1 < len(resources.Content); i += 2 {
+		if _, ok := t.Resources[resources.Content[i].Value]; ok {
+			names = append(names, resources.Content[i].Value)
+		}
+	}
+	return names
+}
+
+func (t *cfnTemplate) resourceType(name string) string {
+	resource, _ := t.Resources[name].(map[string]interface{})
+	resourceType, _ := resource["Type"].(string)
+	return resourceType
+}
+
+// dotQuote returns s as a DOT quoted string. Escape sequences such as \n are
+// kept, so that they can be used in labels.
+func dotQuote(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+}
+
+func mermaidEscape(s string) string {
+	return strings.ReplaceAll(s, `"`, "#quot;")
+}
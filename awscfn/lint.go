@@ -0,0 +1,138 @@
+package awscfn
+
+import (
+	"fmt"
+)
+
+// Rules checked by lint. CloudFormation rejects templates with undefined
+// references when a stack is created or updated, while unused declarations
+// are only reported as warnings.
+const (
+	lintRuleUndefinedReference  = "undefined_reference"
+	lintRuleUndefinedResource   = "undefined_resource"
+	lintRuleUndefinedMapping    = "undefined_mapping"
+	lintRuleUndefinedMappingKey = "undefined_mapping_key"
+	lintRuleUndefinedCondition  = "undefined_condition"
+	lintRuleUnusedParameter     = "unused_parameter"
+	lintRuleUnusedMapping       = "unused_mapping"
+	lintRuleUnusedCondition     = "unused_condition"
+
+	lintSeverityError   = "error"
+	lintSeverityWarning = "warning"
+)
+
+// lintFinding is a problem found in a template by lint
+type lintFinding struct {
+	Rule     string
+	Severity string
+	Message  string
+	// The section and logical ID of the member the finding is about, i.e.
+	// the source of an undefined reference or the unused declaration
+	Section      string
+	Name         string
+	PropertyPath string
+	TargetName   string
+	Line         int
+}
+
+// lint checks the references made in the template, and returns references to
+// parameters, resources, mappings, mapping keys and conditions that are not
+// declared, followed by parameters, mappings and conditions that are declared
+// but never referenced. References to resources generated by the SAM
+// transform are declared. Other transforms and macros may declare anything,
+// so undefined references in templates with a Transform are only warnings.
+func (t *cfnTemplate) lint() []lintFinding {
+	var findings []lintFinding
+	undefinedSeverity := lintSeverityError
+	if t.Transform != nil {
+		undefinedSeverity = lintSeverityWarning
+	}
+	used := map[string]map[string]bool{
+		"Parameters": {},
+		"Mappings":   {},
+		"Conditions": {},
+	}
+
+	for _, r := range t.references() {
+		if used[r.TargetSection] != nil {
+			used[r.TargetSection][r.TargetName] = true
+		}
+
+		finding := lintFinding{
+			Severity:     undefinedSeverity,
+			Section:      r.SourceSection,
+			Name:         r.SourceName,
+			PropertyPath: r.PropertyPath,
+			TargetName:   r.TargetName,
+			Line:         r.Line,
+		}
+
+		switch {
+		case r.TargetSection == "Mappings":
+			if r.mappingKeys == nil || r.hasDefaultValue || t.hasMappingKeys(r.TargetName, r.mappingKeys) {
+				continue
+			}
+			finding.Rule = lintRuleUndefinedMappingKey
+			finding.Message = fmt.Sprintf("%s %s does not contain the keys %s and %s", r.Function, r.TargetName, r.mappingKeys[0], r.mappingKeys[1])
+		case r.TargetSection != "":
+			continue
+		case r.Function == "Fn::FindInMap":
+			finding.Rule = lintRuleUndefinedMapping
+			finding.Message = fmt.Sprintf("%s refers to mapping %s, which is not declared", r.Function, r.TargetName)
+		case r.Function == "Fn::If" || r.Function == "Condition":
+			finding.Rule = lintRuleUndefinedCondition
+			finding.Message = fmt.Sprintf("%s refers to condition %s, which is not declared", r.Function, r.TargetName)
+		case r.Function == "Fn::GetAtt" || r.Function == "DependsOn" || r.TargetAttribute != "":
+			finding.Rule = lintRuleUndefinedResource
+			finding.Message = fmt.Sprintf("%s refers to resource %s, which is not declared", r.Function, r.TargetName)
+		default:
+			finding.Rule = lintRuleUndefinedReference
+			finding.Message = fmt.Sprintf("%s refers to %s, which is not a declared parameter, resource or pseudo parameter", r.Function, r.TargetName)
+		}
+		findings = append(findings, finding)
+	}
+
+	unused := []struct {
+		section      string
+		declarations map[string]interface{}
+		rule         string
+		kind         string
+	}{
+		{"Parameters", t.Parameters, lintRuleUnusedParameter, "parameter"},
+		{"Mappings", t.Mappings, lintRuleUnusedMapping, "mapping"},
+		{"Conditions", t.Conditions, lintRuleUnusedCondition, "condition"},
+	}
+	for _, u := range unused {
+		for _, name := range sortedKeys(u.declarations) {
+			if used[u.section][name] {
+				continue
+			}
+			line, _ := t.keyPosition(u.section, name)
+			findings = append(findings, lintFinding{
+				Rule:     u.rule,
+				Severity: lintSeverityWarning,
+				Message:  fmt.Sprintf("The %s %s is declared but never referenced", u.kind, name),
+				Section:  u.section,
+				Name:     name,
+				Line:     line,
+			})
+		}
+	}
+
+	return findings
+}
+
+// hasMappingKeys returns true if the mapping contains the given top level and
+// second level keys
+func (t *cfnTemplate) hasMappingKeys(name string, keys []string) bool {
+	mapping, ok := t.Mappings[name].(map[string]interface{})
+	if !ok {
+		return false
+	}
+	values, ok := mapping[keys[0]].(map[string]interface{})
+	if !ok {
+		return false
+	}
+	_, ok = values[keys[1]]
+	return ok
+}
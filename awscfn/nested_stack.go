@@ -0,0 +1,184 @@
+package awscfn
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+
+	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/transform"
+)
+
+const nestedStackResourceType = "AWS::CloudFormation::Stack"
+
+// nestedStack is an AWS::CloudFormation::Stack resource declared in a template
+type nestedStack struct {
+	LogicalID   string
+	TemplateURL interface{}
+	// The local template file the TemplateURL refers to, or an empty string if
+	// it is a remote URL, e.g. an S3 URL, or not a literal string
+	TemplatePath string
+	// The Parameters property of the resource
+	Parameters map[string]interface{}
+	Line       int
+}
+
+// nestedStacks returns the nested stack resources of the template, in the
+// order they are declared in the file
+func (t *cfnTemplate) nestedStacks() []nestedStack {
+	var stacks []nestedStack
+	for _, name := range t.resourceNames() {
+		if t.resourceType(name) != nestedStackResourceType {
+			continue
+		}
+		resource := t.Resources[name].(map[string]interface{})
+		properties, _ := resource["Properties"].(map[string]interface{})
+		parameters, _ := properties["Parameters"].(map[string]interface{})
+
+		line, _ := t.keyPosition("Resources", name)
+		stack := nestedStack{
+			LogicalID:   name,
+			TemplateURL: properties["TemplateURL"],
+			Parameters:  parameters,
+			Line:        line,
+		}
+		if url, ok := properties["TemplateURL"].(string); ok {
+			stack.TemplatePath = resolveTemplateURL(t.Path, url)
+		}
+		stacks = append(stacks, stack)
+	}
+	return stacks
+}
+
+// resolveTemplateURL returns the local file a TemplateURL refers to, resolving
+// relative paths against the directory of the parent template, as done by
+// aws cloudformation package. Remote URLs, e.g. https:// or s3:// URLs, return
+// an empty string.
+func resolveTemplateURL(parentPath, url string) string {
+	url = strings.TrimSpace(url)
+	if strings.HasPrefix(url, "file://") {
+		url = strings.TrimPrefix(url, "file://")
+	} else if url == "" || strings.Contains(url, "://") {
+		return ""
+	}
+	if !filepath.IsAbs(url) {
+		url = filepath.Join(filepath.Dir(parentPath), url)
+	}
+	return filepath.Clean(url)
+}
+
+// stackPosition is the position of a template in the nested stack hierarchy
+type stackPosition struct {
+	// The template that declares the nested stack for this template, and the
+	// logical ID of the stack resource in that template
+	ParentPath     string
+	StackLogicalID string
+	// The number of ancestors of the template, i.e. 0 for root templates
+	Depth int
+}
+
+// getStackHierarchy returns the position in the nested stack hierarchy of
+// every configured template that is nested by another configured template,
+// keyed by the cleaned path of the template. If a template is nested by more
+// than one stack, the first stack found is used. Building the hierarchy lists
+// and parses every configured template, even if the query is limited to a
+// path, so it is empty unless one of the stack position columns is requested.
+func getStackHierarchy(ctx context.Context, d *plugin.QueryData) (map[string]stackPosition, error) {
+	hierarchy := map[string]stackPosition{}
+
+	// A path qual can be used without configured paths
+	if GetConfig(d.Connection).Paths == nil || !stackPositionRequested(d) {
+		return hierarchy, nil
+	}
+	paths, err := listFilesByPath(ctx, d)
+	if err != nil {
+		return nil, err
+	}
+
+	listed := map[string]bool{}
+	for _, path := range paths {
+		listed[filepath.Clean(path)] = true
+	}
+
+	for _, path := range paths {
+		template, err := getTemplate(ctx, d, path)
+		if err != nil {
+			continue
+		}
+		for _, stack := range template.nestedStacks() {
+			if !listed[stack.TemplatePath] || stack.TemplatePath == filepath.Clean(path) {
+				continue
+			}
+			if _, ok := hierarchy[stack.TemplatePath]; ok {
+				continue
+			}
+			hierarchy[stack.TemplatePath] = stackPosition{
+				ParentPath:     path,
+				StackLogicalID: stack.LogicalID,
+			}
+		}
+	}
+
+	for path, position := range hierarchy {
+		// Count the ancestors, stopping if the templates nest each other
+		visited := map[string]bool{path: true}
+		for parent := filepath.Clean(position.ParentPath); !visited[parent]; {
+			visited[parent] = true
+			position.Depth++
+			next, ok := hierarchy[parent]
+			if !ok {
+				break
+			}
+			parent = filepath.Clean(next.ParentPath)
+		}
+		hierarchy[path] = position
+	}
+
+	return hierarchy, nil
+}
+
+// stackPositionRequested returns true if the query requests any of the
+// columns returned by stackPositionColumns
+func stackPositionRequested(d *plugin.QueryData) bool {
+	if d.QueryContext == nil {
+		return true
+	}
+	for _, column := range d.QueryContext.Columns {
+		switch column {
+		case "parent_path", "stack_logical_id", "depth":
+			return true
+		}
+	}
+	return false
+}
+
+// stackPositionOf returns the position of the template file in the hierarchy
+func stackPositionOf(hierarchy map[string]stackPosition, path string) stackPosition {
+	return hierarchy[filepath.Clean(path)]
+}
+
+// stackPositionColumns returns the columns describing the position of the
+// template in the nested stack hierarchy, shared by all template tables
+func stackPositionColumns() []*plugin.Column {
+	return []*plugin.Column{
+		{
+			Name:        "parent_path",
+			Description: "Path to the parent template, if the template is used by an AWS::CloudFormation::Stack resource with a local TemplateURL in another template.",
+			Type:        proto.ColumnType_STRING,
+			Transform:   transform.FromField("ParentPath").NullIfZero(),
+		},
+		{
+			Name:        "stack_logical_id",
+			Description: "The logical ID of the AWS::CloudFormation::Stack resource in the parent template.",
+			Type:        proto.ColumnType_STRING,
+			Transform:   transform.FromField("StackLogicalID").NullIfZero(),
+		},
+		{
+			Name:        "depth",
+			Description: "The depth of the template in the nested stack hierarchy, i.e. 0 for root templates, 1 for their nested stacks and so on.",
+			Type:        proto.ColumnType_INT,
+			Transform:   transform.FromField("Depth"),
+		},
+	}
+}
@@ -0,0 +1,189 @@
+package awscfn
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+	"gopkg.in/yaml.v3"
+)
+
+// Formats of parameter files
+const (
+	// The format used by `aws cloudformation create-stack --parameters`, e.g.
+	// [{"ParameterKey": "Env", "ParameterValue": "prod"}]
+	parameterFileFormatCLI = "cli"
+	// The template configuration format used by CodePipeline, e.g.
+	// {"Parameters": {"Env": "prod"}}
+	parameterFileFormatCodePipeline = "codepipeline"
+	// The format used by `aws cloudformation deploy --parameter-overrides`,
+	// i.e. Key=Value lines or a JSON list of Key=Value strings
+	parameterFileFormatKeyValue = "key_value"
+)
+
+// parameterFileValue is a single parameter value declared in a parameter file
+type parameterFileValue struct {
+	Key   string
+	Value string
+	Line  int
+}
+
+// parseParameterFile parses a file of CloudFormation parameter values, and
+// returns the values and the format of the file
+func parseParameterFile(content []byte) ([]parameterFileValue, string, error) {
+	trimmed := bytes.TrimSpace(content)
+	if bytes.HasPrefix(trimmed, []byte("[")) || bytes.HasPrefix(trimmed, []byte("{")) {
+		return parseJSONParameterFile(content)
+	}
+	values, err := parseKeyValueParameterFile(content)
+	return values, parameterFileFormatKeyValue, err
+}
+
+// parseJSONParameterFile parses a parameter file in the CLI or CodePipeline
+// format. The file is decoded as YAML, which is a superset of JSON, to keep
+// the line numbers of the values.
+func parseJSONParameterFile(content []byte) ([]parameterFileValue, string, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return nil, "", err
+	}
+	if len(doc.Content) == 0 {
+		return nil, "", errors.New("parameter file is empty")
+	}
+	root := doc.Content[0]
+
+	var values []parameterFileValue
+	switch root.Kind {
+	case yaml.SequenceNode:
+		format := parameterFileFormatCLI
+		for _, item := range root.Content {
+			// The deploy command also accepts a JSON list of Key=Value strings
+			if item.Kind == yaml.ScalarNode {
+				value, err := parseKeyValue(item.Value)
+				if err != nil {
+					return nil, "", err
+				}
+				value.Line = item.Line
+				values = append(values, value)
+				format = parameterFileFormatKeyValue
+				continue
+			}
+
+			key := mappingValue(item, "ParameterKey")
+			if key == nil || key.Kind != yaml.ScalarNode || key.Value == "" {
+				return nil, "", fmt.Errorf("parameter on line %d is missing ParameterKey", item.Line)
+			}
+			// Previous values are only known to CloudFormation
+			if previous := mappingValue(item, "UsePreviousValue"); previous != nil && previous.Value == "true" {
+				continue
+			}
+			value := mappingValue(item, "ParameterValue")
+			if value != nil && value.Kind != yaml.ScalarNode {
+				return nil, "", fmt.Errorf("ParameterValue of parameter %s must be a string", key.Value)
+			}
+			v := parameterFileValue{Key: key.Value, Line: item.Line}
+			if value != nil {
+				v.Value = value.Value
+			}
+			values = append(values, v)
+		}
+		return values, format, nil
+
+	case yaml.MappingNode:
+		parameters := mappingValue(root, "Parameters")
+		if parameters == nil {
+			return nil, "", errors.New("parameter file has no Parameters object")
+		}
+		if parameters.Kind != yaml.MappingNode {
+			return nil, "", errors.New("parameter file Parameters value must be an object")
+		}
+		for i := 0; i+1 < len(parameters.Content); i += 2 {
+			key, value := parameters.Content[i], parameters.Content[i+1]
+			if value.Kind != yaml.ScalarNode {
+				return nil, "", fmt.Errorf("value of parameter %s must be a string", key.Value)
+			}
+			values = append(values, parameterFileValue{Key: key.Value, Value: value.Value, Line: key.Line})
+		}
+		return values, parameterFileFormatCodePipeline, nil
+	}
+
+	return nil, "", errors.New("parameter file must be a list or an object")
+}
+
+// mappingValue returns the value node of the key in a mapping node, or nil
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func parseKeyValueParameterFile(content []byte) ([]parameterFileValue, error) {
+	var values []parameterFileValue
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		value, err := parseKeyValue(line)
+		if err != nil {
+			return nil, err
+		}
+		value.Line = lineNo
+		values = append(values, value)
+	}
+	return values, scanner.Err()
+}
+
+func parseKeyValue(s string) (parameterFileValue, error) {
+	key, value, ok := strings.Cut(s, "=")
+	key = strings.TrimSpace(key)
+	if !ok || key == "" {
+		return parameterFileValue{}, fmt.Errorf("invalid parameter %q, expected Key=Value", s)
+	}
+	value = strings.TrimSpace(value)
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		value = value[1 : len(value)-1]
+	}
+	return parameterFileValue{Key: key, Value: value}, nil
+}
+
+// readParameterValues adds the values declared in the parameter files, and
+// then the given values, to the parameter values. Files later in the list take
+// precedence over earlier ones, and the given values over all files.
+func readParameterValues(ctx context.Context, parameterValues map[string]string, files []string, values map[string]string) error {
+	for _, path := range files {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			plugin.Logger(ctx).Error("readParameterValues", "file_error", err, "path", path)
+			return fmt.Errorf("failed to read parameter file %s: %v", path, err)
+		}
+		fileValues, _, err := parseParameterFile(content)
+		if err != nil {
+			plugin.Logger(ctx).Error("readParameterValues", "parse_error", err, "path", path)
+			return fmt.Errorf("failed to parse parameter file %s: %v", path, err)
+		}
+		for _, v := range fileValues {
+			parameterValues[v.Key] = v.Value
+		}
+	}
+
+	for k, v := range values {
+		parameterValues[k] = v
+	}
+
+	return nil
+}
@@ -0,0 +1,58 @@
+package awscfn
+
+import (
+	"strings"
+)
+
+// parameterType is a parsed CloudFormation parameter type, e.g.
+// AWS::SSM::Parameter::Value<List<AWS::EC2::Subnet::Id>>
+type parameterType struct {
+	// The type of the value, or of each item for list types, i.e. String or
+	// Number. AWS-specific parameter types are strings.
+	BaseType string
+	// True for CommaDelimitedList and List<...> types, including SSM
+	// parameters with a list value type
+	IsList bool
+	// True for SSM parameter types, i.e. AWS::SSM::Parameter::Name and
+	// AWS::SSM::Parameter::Value<...>
+	IsSSM bool
+	// The value type of an AWS::SSM::Parameter::Value<...> parameter
+	SSMValueType string
+	// The AWS-specific parameter type, with any SSM and list wrappers
+	// removed, e.g. AWS::EC2::Subnet::Id
+	AWSResourceType string
+}
+
+const ssmParameterValuePrefix = "AWS::SSM::Parameter::Value<"
+
+func parseParameterType(typeName string) parameterType {
+	t := parameterType{BaseType: "String"}
+
+	name := strings.TrimSpace(typeName)
+	if strings.HasPrefix(name, ssmParameterValuePrefix) && strings.HasSuffix(name, ">") {
+		t.IsSSM = true
+		name = strings.TrimSpace(name[len(ssmParameterValuePrefix) : len(name)-1])
+		t.SSMValueType = name
+	}
+
+	switch {
+	case name == "CommaDelimitedList":
+		t.IsList = true
+		return t
+	case strings.HasPrefix(name, "List<") && strings.HasSuffix(name, ">"):
+		t.IsList = true
+		name = strings.TrimSpace(name[len("List<") : len(name)-1])
+	}
+
+	switch {
+	case name == "Number":
+		t.BaseType = "Number"
+	case name == "AWS::SSM::Parameter::Name":
+		t.IsSSM = true
+		t.AWSResourceType = name
+	case strings.HasPrefix(name, "AWS::"):
+		t.AWSResourceType = name
+	}
+
+	return t
+}
@@ -0,0 +1,120 @@
+package awscfn
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// validateParameterValue checks a parameter value against the constraints
+// declared for the parameter, i.e. AllowedPattern, AllowedValues, MinLength,
+// MaxLength, MinValue and MaxValue, and returns the constraints it violates.
+// Values of list types are validated item by item. AllowedPattern is a Java
+// regular expression, so patterns that RE2 can not compile, e.g. lookaheads,
+// are skipped and complete is false.
+func validateParameterValue(parameter map[string]interface{}, value interface{}) (validationErrors []string, complete bool) {
+	validationErrors = []string{}
+	complete = true
+
+	typeName, _ := parameter["Type"].(string)
+	t := parseParameterType(typeName)
+	// The value of an SSM parameter type is the name of the SSM parameter
+	isNumber := t.BaseType == "Number" && !t.IsSSM
+
+	var items []string
+	switch v := parameterValue(typeName, value).(type) {
+	case string:
+		items = []string{v}
+	case []interface{}:
+		for _, item := range v {
+			items = append(items, scalarString(item))
+		}
+	default:
+		return append(validationErrors, "value must be a string, number or list"), true
+	}
+
+	var pattern *regexp.Regexp
+	if s, ok := parameter["AllowedPattern"].(string); ok {
+		var err error
+		// The pattern must match the whole value
+		pattern, err = regexp.Compile("^(?:" + s + ")$")
+		if err != nil {
+			complete = false
+		}
+	}
+
+	var allowedValues []string
+	if list, ok := parameter["AllowedValues"].([]interface{}); ok {
+		for _, v := range list {
+			allowedValues = append(allowedValues, scalarString(v))
+		}
+	}
+
+	minLength, hasMinLength := numberValue(parameter["MinLength"])
+	maxLength, hasMaxLength := numberValue(parameter["MaxLength"])
+	minValue, hasMinValue := numberValue(parameter["MinValue"])
+	maxValue, hasMaxValue := numberValue(parameter["MaxValue"])
+
+	for _, item := range items {
+		if pattern != nil && !pattern.MatchString(item) {
+			validationErrors = append(validationErrors, fmt.Sprintf("value %q does not match AllowedPattern %q", item, parameter["AllowedPattern"]))
+		}
+		if allowedValues != nil && !containsString(allowedValues, item) {
+			validationErrors = append(validationErrors, fmt.Sprintf("value %q is not one of AllowedValues", item))
+		}
+
+		// Length constraints only apply to String parameters
+		if typeName == "String" {
+			length := float64(utf8.RuneCountInString(item))
+			if hasMinLength && length < minLength {
+				validationErrors = append(validationErrors, fmt.Sprintf("value %q is shorter than MinLength %s", item, scalarString(minLength)))
+			}
+			if hasMaxLength && length > maxLength {
+				validationErrors = append(validationErrors, fmt.Sprintf("value %q is longer than MaxLength %s", item, scalarString(maxLength)))
+			}
+		}
+
+		if !isNumber {
+			continue
+		}
+		n, err := strconv.ParseFloat(strings.TrimSpace(item), 64)
+		if err != nil {
+			validationErrors = append(validationErrors, fmt.Sprintf("value %q is not a number", item))
+			continue
+		}
+		if hasMinValue && n < minValue {
+			validationErrors = append(validationErrors, fmt.Sprintf("value %q is less than MinValue %s", item, scalarString(minValue)))
+		}
+		if hasMaxValue && n > maxValue {
+			validationErrors = append(validationErrors, fmt.Sprintf("value %q is greater than MaxValue %s", item, scalarString(maxValue)))
+		}
+	}
+
+	return validationErrors, complete
+}
+
+// numberValue returns the value of a numeric attribute, which may be declared
+// as a number or a string, e.g. MaxLength: "10"
+func numberValue(v interface{}) (float64, bool) {
+	switch value := v.(type) {
+	case float64:
+		return value, true
+	case int:
+		return float64(value), true
+	case string:
+		n, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		return n, err == nil
+	}
+	return 0, false
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
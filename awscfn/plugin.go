@@ -18,10 +18,20 @@ func Plugin(ctx context.Context) *plugin.Plugin {
 			NewInstance: ConfigInstance,
 		},
 		TableMap: map[string]*plugin.Table{
-			"awscfn_mapping":   tableAWSCFNMapping(ctx),
-			"awscfn_output":    tableAWSCFNOutput(ctx),
-			"awscfn_parameter": tableAWSCFNParameter(ctx),
-			"awscfn_resource":  tableAWSCFNResource(ctx),
+			"awscfn_condition":      tableAWSCFNCondition(ctx),
+			"awscfn_dependency":     tableAWSCFNDependency(ctx),
+			"awscfn_lint_finding":   tableAWSCFNLintFinding(ctx),
+			"awscfn_mapping":        tableAWSCFNMapping(ctx),
+			"awscfn_nested_stack":   tableAWSCFNNestedStack(ctx),
+			"awscfn_output":         tableAWSCFNOutput(ctx),
+			"awscfn_parameter":      tableAWSCFNParameter(ctx),
+			"awscfn_parameter_file": tableAWSCFNParameterFile(ctx),
+			"awscfn_parse_error":    tableAWSCFNParseError(ctx),
+			"awscfn_reference":      tableAWSCFNReference(ctx),
+			"awscfn_resource":       tableAWSCFNResource(ctx),
+			"awscfn_sam_event":      tableAWSCFNSAMEvent(ctx),
+			"awscfn_sam_global":     tableAWSCFNSAMGlobal(ctx),
+			"awscfn_template":       tableAWSCFNTemplate(ctx),
 		},
 	}
 
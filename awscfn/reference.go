@@ -0,0 +1,297 @@
+package awscfn
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// templateReference is a reference from one member of a template section,
+// e.g. a resource, to a parameter, resource, mapping or condition
+type templateReference struct {
+	SourceSection string
+	SourceName    string
+	// The path of the reference within the source, e.g.
+	// Properties.Tags[0].Value
+	PropertyPath string
+	// The function or attribute that makes the reference, i.e. Ref,
+	// Fn::GetAtt, Fn::Sub, Fn::FindInMap, Fn::If, Condition or DependsOn
+	Function        string
+	TargetName      string
+	TargetAttribute string
+	// The section the target is declared in, i.e. Parameters, Resources,
+	// Mappings or Conditions, PseudoParameters for pseudo parameters such as
+	// AWS::Region, or empty if the target is not declared. Resources include
+	// the resources generated by the SAM transform.
+	TargetSection string
+	Line          int
+
+	// The top level and second level keys of a Fn::FindInMap lookup, if they
+	// are literals
+	mappingKeys []string
+	// True if a Fn::FindInMap lookup declares a DefaultValue, which is used
+	// instead if the keys are not found
+	hasDefaultValue bool
+}
+
+// Sections that may contain references, in template order
+var referenceSourceSections = []string{"Rules", "Conditions", "Resources", "Outputs"}
+
+var pseudoParameterNames = map[string]bool{
+	"AWS::AccountId":        true,
+	"AWS::NotificationARNs": true,
+	"AWS::NoValue":          true,
+	"AWS::Partition":        true,
+	"AWS::Region":           true,
+	"AWS::StackId":          true,
+	"AWS::StackName":        true,
+	"AWS::URLSuffix":        true,
+}
+
+// references returns all references made in the template, ordered by section
+// and position in the file
+func (t *cfnTemplate) references() []templateReference {
+	root := &t.Root
+	if root.Kind == yaml.DocumentNode && len(root.Content) > 0 {
+		root = root.Content[0]
+	}
+
+	w := &referenceWalker{template: t, generated: t.samGeneratedResources()}
+	for _, section := range referenceSourceSections {
+		node := mappingValue(root, section)
+		if node == nil || node.Kind != yaml.MappingNode {
+			continue
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			w.section, w.name = section, node.Content[i].Value
+			w.walkSource(node.Content[i+1])
+		}
+	}
+	return w.references
+}
+
+type referenceWalker struct {
+	template *cfnTemplate
+	// Resources generated by the SAM transform, which may be referenced
+	// although they are not declared
+	generated  map[string]bool
+	section    string
+	name       string
+	references []templateReference
+}
+
+func (w *referenceWalker) add(path, function, target, attribute string, line int) {
+	w.references = append(w.references, templateReference{
+		SourceSection:   w.section,
+		SourceName:      w.name,
+		PropertyPath:    path,
+		Function:        function,
+		TargetName:      target,
+		TargetAttribute: attribute,
+		TargetSection:   w.targetSection(function, target, attribute),
+		Line:            line,
+	})
+}
+
+// walkSource walks a member of a section. The Condition and DependsOn
+// attributes of resources and outputs are references.
+func (w *referenceWalker) walkSource(node *yaml.Node) {
+	// Conditions are defined by a single condition function
+	if node.Kind != yaml.MappingNode || w.section == "Conditions" {
+		w.walk(node, "")
+		return
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		switch {
+		case key.Value == "Condition" && value.Kind == yaml.ScalarNode:
+			w.add(key.Value, "Condition", value.Value, "", value.Line)
+		case key.Value == "DependsOn" && w.section == "Resources":
+			w.walkDependsOn(value)
+		default:
+			w.walk(value, key.Value)
+		}
+	}
+}
+
+func (w *referenceWalker) walkDependsOn(node *yaml.Node) {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		w.add("DependsOn", "DependsOn", node.Value, "", node.Line)
+	case yaml.SequenceNode:
+		for i, item := range node.Content {
+			if item.Kind == yaml.ScalarNode {
+				w.add(fmt.Sprintf("DependsOn[%d]", i), "DependsOn", item.Value, "", item.Line)
+			}
+		}
+	}
+}
+
+func (w *referenceWalker) walk(node *yaml.Node, path string) {
+	switch node.Kind {
+	case yaml.AliasNode:
+		if node.Alias != nil {
+			w.walk(node.Alias, path)
+		}
+	case yaml.SequenceNode:
+		for i, item := range node.Content {
+			w.walk(item, fmt.Sprintf("%s[%d]", path, i))
+		}
+	case yaml.MappingNode:
+		if len(node.Content) == 2 && w.walkFunction(node.Content[0].Value, node.Content[1], path, node.Line) {
+			return
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			w.walk(node.Content[i+1], joinPropertyPath(path, node.Content[i].Value))
+		}
+	}
+}
+
+// walkFunction records the references made by an intrinsic function, and
+// returns false if the node is not a function call
+func (w *referenceWalker) walkFunction(fn string, args *yaml.Node, path string, line int) bool {
+	argsPath := joinPropertyPath(path, fn)
+
+	switch fn {
+	case "Ref":
+		if args.Kind != yaml.ScalarNode {
+			return false
+		}
+		w.add(path, fn, args.Value, "", line)
+		return true
+
+	case "Condition":
+		// Condition is also a property name, e.g. in IAM policy statements,
+		// but only takes a string when used as a function
+		if args.Kind != yaml.ScalarNode {
+			return false
+		}
+		w.add(path, fn, args.Value, "", line)
+		return true
+
+	case "Fn::GetAtt":
+		switch {
+		case args.Kind == yaml.ScalarNode:
+			resource, attribute, _ := strings.Cut(args.Value, ".")
+			w.add(path, fn, resource, attribute, line)
+		case args.Kind == yaml.SequenceNode && len(args.Content) > 0 && args.Content[0].Kind == yaml.ScalarNode:
+			// The attribute name may itself be a function, e.g. a Ref
+			var attribute string
+			if len(args.Content) > 1 && args.Content[1].Kind == yaml.ScalarNode {
+				attribute = args.Content[1].Value
+			}
+			w.add(path, fn, args.Content[0].Value, attribute, line)
+			for i, item := range args.Content[1:] {
+				w.walk(item, fmt.Sprintf("%s[%d]", argsPath, i+1))
+			}
+		default:
+			w.walk(args, argsPath)
+		}
+		return true
+
+	case "Fn::Sub":
+		switch {
+		case args.Kind == yaml.ScalarNode:
+			w.addSubPlaceholders(path, args, nil)
+		case args.Kind == yaml.SequenceNode && len(args.Content) == 2 && args.Content[0].Kind == yaml.ScalarNode:
+			variables := map[string]bool{}
+			if vars := args.Content[1]; vars.Kind == yaml.MappingNode {
+				for i := 0; i+1 < len(vars.Content); i += 2 {
+					variables[vars.Content[i].Value] = true
+				}
+			}
+			w.addSubPlaceholders(path, args.Content[0], variables)
+			w.walk(args.Content[1], argsPath+"[1]")
+		default:
+			w.walk(args, argsPath)
+		}
+		return true
+
+	case "Fn::FindInMap":
+		if args.Kind == yaml.SequenceNode && len(args.Content) > 0 && args.Content[0].Kind == yaml.ScalarNode {
+			// The keys are only known if they are literals
+			var attribute string
+			var keys []string
+			if len(args.Content) > 2 && args.Content[1].Kind == yaml.ScalarNode && args.Content[2].Kind == yaml.ScalarNode {
+				keys = []string{args.Content[1].Value, args.Content[2].Value}
+				attribute = strings.Join(keys, ".")
+			}
+			w.add(path, fn, args.Content[0].Value, attribute, line)
+			reference := &w.references[len(w.references)-1]
+			reference.mappingKeys = keys
+			reference.hasDefaultValue = len(args.Content) > 3 && mappingValue(args.Content[3], "DefaultValue") != nil
+			for i, item := range args.Content[1:] {
+				w.walk(item, fmt.Sprintf("%s[%d]", argsPath, i+1))
+			}
+			return true
+		}
+
+	case "Fn::If":
+		if args.Kind == yaml.SequenceNode && len(args.Content) > 0 && args.Content[0].Kind == yaml.ScalarNode {
+			w.add(path, fn, args.Content[0].Value, "", line)
+			for i, item := range args.Content[1:] {
+				w.walk(item, fmt.Sprintf("%s[%d]", argsPath, i+1))
+			}
+			return true
+		}
+	}
+
+	if !strings.HasPrefix(fn, "Fn::") {
+		return false
+	}
+	w.walk(args, argsPath)
+	return true
+}
+
+// addSubPlaceholders records the references made by the placeholders of a
+// Fn::Sub string, e.g. ${MyParameter} or ${MyBucket.Arn}, except literals,
+// e.g. ${!Literal}, and variables declared in the Fn::Sub variable map
+func (w *referenceWalker) addSubPlaceholders(path string, node *yaml.Node, variables map[string]bool) {
+	for _, match := range subPlaceholderRegex.FindAllStringSubmatch(node.Value, -1) {
+		name := strings.TrimSpace(match[1])
+		if name == "" || strings.HasPrefix(name, "!") || variables[name] {
+			continue
+		}
+		target, attribute, _ := strings.Cut(name, ".")
+		w.add(path, "Fn::Sub", target, attribute, node.Line)
+	}
+}
+
+// targetSection returns the section that the target of a reference is
+// declared in, or an empty string if it is not declared
+func (w *referenceWalker) targetSection(function, target, attribute string) string {
+	t := w.template
+	switch function {
+	case "Fn::GetAtt", "DependsOn":
+		if _, ok := t.Resources[target]; ok || w.generated[target] {
+			return "Resources"
+		}
+	case "Fn::FindInMap":
+		if _, ok := t.Mappings[target]; ok {
+			return "Mappings"
+		}
+	case "Fn::If", "Condition":
+		if _, ok := t.Conditions[target]; ok {
+			return "Conditions"
+		}
+	case "Ref", "Fn::Sub":
+		if _, ok := t.Parameters[target]; ok && attribute == "" {
+			return "Parameters"
+		}
+		if _, ok := t.Resources[target]; ok || w.generated[target] {
+			return "Resources"
+		}
+		if pseudoParameterNames[target] && attribute == "" {
+			return "PseudoParameters"
+		}
+	}
+	return ""
+}
+
+func joinPropertyPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
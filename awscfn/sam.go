@@ -0,0 +1,974 @@
+package awscfn
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// The AWS Serverless Application Model (SAM) transform
+const samTransform = "AWS::Serverless-2016-10-31"
+
+// Logical IDs of the APIs that SAM creates for Api and HttpApi events that do
+// not refer to an API declared in the template
+const (
+	samImplicitRestAPI = "ServerlessRestApi"
+	samImplicitHTTPAPI = "ServerlessHttpApi"
+)
+
+// samGlobalsResourceTypes maps the sections of the Globals section to the
+// resource types they apply to
+var samGlobalsResourceTypes = map[string]string{
+	"Api":              "AWS::Serverless::Api",
+	"CapacityProvider": "AWS::Serverless::CapacityProvider",
+	"Function":         "AWS::Serverless::Function",
+	"HttpApi":          "AWS::Serverless::HttpApi",
+	"LayerVersion":     "AWS::Serverless::LayerVersion",
+	"SimpleTable":      "AWS::Serverless::SimpleTable",
+	"StateMachine":     "AWS::Serverless::StateMachine",
+}
+
+// resourceDefinition is a resource of a template. Resources generated by
+// expanding a SAM resource record the logical ID of that resource.
+type resourceDefinition struct {
+	Name         string
+	Data         map[string]interface{}
+	ExpandedFrom string
+}
+
+// isServerless returns true if the template uses the SAM transform
+func (t *cfnTemplate) isServerless() bool {
+	switch transform := t.Transform.(type) {
+	case string:
+		return transform == samTransform
+	case []interface{}:
+		for _, item := range transform {
+			if item == samTransform {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// samGeneratedResources returns the logical IDs of the resources the SAM
+// transform generates that are not declared in the template, e.g. the role of
+// a function or the implicit ServerlessRestApi, or nil if the template does
+// not use the SAM transform
+func (t *cfnTemplate) samGeneratedResources() map[string]bool {
+	if !t.isServerless() {
+		return nil
+	}
+	generated := map[string]bool{}
+	for _, resource := range newSAMExpander(t).expand() {
+		if _, ok := t.Resources[resource.Name]; !ok {
+			generated[resource.Name] = true
+		}
+	}
+	return generated
+}
+
+// resourceDefinitions returns the resources of the template in the order
+// they are declared in the file. If expandServerless is set and the template
+// uses the SAM transform, SAM resources are replaced by the CloudFormation
+// resources the transform generates for them.
+func (t *cfnTemplate) resourceDefinitions(expandServerless bool) []resourceDefinition {
+	if expandServerless && t.isServerless() {
+		return newSAMExpander(t).expand()
+	}
+
+	var resources []resourceDefinition
+	for _, name := range t.resourceNames() {
+		if data, ok := t.Resources[name].(map[string]interface{}); ok {
+			resources = append(resources, resourceDefinition{Name: name, Data: data})
+		}
+	}
+	return resources
+}
+
+// samExpander expands the SAM resources of a template. The expansion follows
+// the SAM transform for the most common resources, properties and event
+// sources, and the most common SAM policy templates, but does not expand
+// connectors or authorizers, and uses fixed logical IDs where SAM appends a
+// hash.
+type samExpander struct {
+	template  *cfnTemplate
+	resources []resourceDefinition
+	// The paths of Api and HttpApi events, keyed by the logical ID of the API
+	apiPaths map[string]map[string]interface{}
+	// The first function with an event for each implicit API
+	implicitAPIs map[string]string
+}
+
+func newSAMExpander(t *cfnTemplate) *samExpander {
+	return &samExpander{
+		template:     t,
+		apiPaths:     map[string]map[string]interface{}{},
+		implicitAPIs: map[string]string{},
+	}
+}
+
+func (e *samExpander) expand() []resourceDefinition {
+	names := e.template.resourceNames()
+
+	// Functions are expanded first, since their Api and HttpApi events add
+	// paths to the APIs
+	expanded := map[string][]resourceDefinition{}
+	for _, name := range names {
+		if e.template.resourceType(name) == "AWS::Serverless::Function" {
+			expanded[name] = e.expandFunction(name, e.properties(name))
+		}
+	}
+
+	for _, name := range names {
+		data, ok := e.template.Resources[name].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		switch e.template.resourceType(name) {
+		case "AWS::Serverless::Function":
+			e.resources = append(e.resources, expanded[name]...)
+		case "AWS::Serverless::Api":
+			e.resources = append(e.resources, e.expandRestAPI(name, e.properties(name))...)
+		case "AWS::Serverless::HttpApi":
+			e.resources = append(e.resources, e.expandHTTPAPI(name, e.properties(name))...)
+		case "AWS::Serverless::SimpleTable":
+			e.resources = append(e.resources, e.expandSimpleTable(name, e.properties(name))...)
+		case "AWS::Serverless::LayerVersion":
+			e.resources = append(e.resources, e.expandLayerVersion(name, e.properties(name))...)
+		case "AWS::Serverless::StateMachine":
+			e.resources = append(e.resources, e.expandStateMachine(name, e.properties(name))...)
+		case "AWS::Serverless::Application":
+			e.resources = append(e.resources, e.expandApplication(name, e.properties(name))...)
+		default:
+			// Other resources, including SAM resources that are not expanded,
+			// are kept as declared
+			e.resources = append(e.resources, resourceDefinition{Name: name, Data: data})
+		}
+	}
+
+	// Implicit APIs are only created if a function has an event for them,
+	// and are configured by the Api and HttpApi globals
+	if source, ok := e.implicitAPIs[samImplicitRestAPI]; ok {
+		properties := mergeSAMGlobals(e.globals("Api"), map[string]interface{}{"StageName": "Prod"})
+		e.addExpanded(e.restAPIResources(samImplicitRestAPI, properties), source)
+	}
+	if source, ok := e.implicitAPIs[samImplicitHTTPAPI]; ok {
+		properties := mergeSAMGlobals(e.globals("HttpApi"), map[string]interface{}{})
+		e.addExpanded(e.httpAPIResources(samImplicitHTTPAPI, properties), source)
+	}
+
+	return e.resources
+}
+
+// properties returns the properties of a SAM resource, with the globals for
+// its type applied
+func (e *samExpander) properties(name string) map[string]interface{} {
+	data, _ := e.template.Resources[name].(map[string]interface{})
+	properties, _ := data["Properties"].(map[string]interface{})
+	for section, resourceType := range samGlobalsResourceTypes {
+		if resourceType == e.template.resourceType(name) {
+			return mergeSAMGlobals(e.globals(section), properties)
+		}
+	}
+	return mergeSAMGlobals(nil, properties)
+}
+
+func (e *samExpander) globals(section string) map[string]interface{} {
+	globals, _ := e.template.Globals[section].(map[string]interface{})
+	return globals
+}
+
+// mergeSAMGlobals returns the properties of a resource with the globals for
+// its type applied, as done by SAM. Properties declared by the resource
+// override the globals, except that maps are merged and the items of global
+// lists are prepended to the items of the resource list. The inputs are not
+// modified.
+func mergeSAMGlobals(globals, properties map[string]interface{}) map[string]interface{} {
+	merged := map[string]interface{}{}
+	for k, v := range globals {
+		merged[k] = v
+	}
+	for k, v := range properties {
+		global, ok := merged[k]
+		if !ok {
+			merged[k] = v
+			continue
+		}
+		globalMap, isGlobalMap := global.(map[string]interface{})
+		valueMap, isValueMap := v.(map[string]interface{})
+		globalList, isGlobalList := global.([]interface{})
+		valueList, isValueList := v.([]interface{})
+		_, _, isGlobalFunction := intrinsicFunction(globalMap)
+		_, _, isValueFunction := intrinsicFunction(valueMap)
+		switch {
+		case isGlobalMap && isValueMap && !isGlobalFunction && !isValueFunction:
+			merged[k] = mergeSAMGlobals(globalMap, valueMap)
+		case isGlobalList && isValueList:
+			merged[k] = append(append([]interface{}{}, globalList...), valueList...)
+		default:
+			merged[k] = v
+		}
+	}
+	return merged
+}
+
+// resource returns the definition of a generated resource. The condition of
+// the SAM resource applies to all the resources generated for it.
+func (e *samExpander) resource(source, resourceType string, properties map[string]interface{}) map[string]interface{} {
+	data := map[string]interface{}{
+		"Type":       resourceType,
+		"Properties": properties,
+	}
+	sourceData, _ := e.template.Resources[source].(map[string]interface{})
+	if condition, ok := sourceData["Condition"]; ok {
+		data["Condition"] = condition
+	}
+	return data
+}
+
+// mainResource returns the definition of the resource that replaces the SAM
+// resource, which also takes its resource attributes
+func (e *samExpander) mainResource(source, resourceType string, properties map[string]interface{}) map[string]interface{} {
+	data := e.resource(source, resourceType, properties)
+	sourceData, _ := e.template.Resources[source].(map[string]interface{})
+	for _, attribute := range []string{"DependsOn", "DeletionPolicy", "UpdateReplacePolicy", "UpdatePolicy", "Metadata"} {
+		if v, ok := sourceData[attribute]; ok {
+			data[attribute] = v
+		}
+	}
+	return data
+}
+
+func (e *samExpander) addExpanded(resources []resourceDefinition, source string) {
+	for _, r := range resources {
+		r.ExpandedFrom = source
+		e.resources = append(e.resources, r)
+	}
+}
+
+// expandFunction expands an AWS::Serverless::Function into an
+// AWS::Lambda::Function, its execution role, and the resources for its
+// alias, function URL and event sources
+func (e *samExpander) expandFunction(name string, p map[string]interface{}) []resourceDefinition {
+	var resources []resourceDefinition
+	add := func(id string, data map[string]interface{}) {
+		resources = append(resources, resourceDefinition{Name: id, Data: data, ExpandedFrom: name})
+	}
+
+	function := map[string]interface{}{}
+	copySAMProperties(function, p, "Architectures", "CodeSigningConfigArn", "Description", "Environment",
+		"EphemeralStorage", "FileSystemConfigs", "FunctionName", "Handler", "ImageConfig", "KmsKeyArn",
+		"Layers", "LoggingConfig", "MemorySize", "PackageType", "ReservedConcurrentExecutions",
+		"RuntimeManagementConfig", "Runtime", "SnapStart", "Timeout", "VpcConfig")
+	switch {
+	case p["InlineCode"] != nil:
+		function["Code"] = map[string]interface{}{"ZipFile": p["InlineCode"]}
+	case p["ImageUri"] != nil:
+		function["Code"] = map[string]interface{}{"ImageUri": p["ImageUri"]}
+	case p["CodeUri"] != nil:
+		function["Code"] = samS3Location(p["CodeUri"], "S3Bucket", "S3Key", "S3ObjectVersion")
+	}
+	if tracing, ok := p["Tracing"]; ok {
+		function["TracingConfig"] = map[string]interface{}{"Mode": tracing}
+	}
+	if dlq, ok := p["DeadLetterQueue"].(map[string]interface{}); ok {
+		function["DeadLetterConfig"] = map[string]interface{}{"TargetArn": dlq["TargetArn"]}
+	}
+	tags := samTags(p["Tags"], "lambda:createdBy")
+	function["Tags"] = tags
+
+	// A role is created unless the function declares one
+	var managedPolicies []interface{}
+	var inlinePolicies []interface{}
+	roleName := name + "Role"
+	if role, ok := p["Role"]; ok {
+		function["Role"] = role
+		roleName = ""
+	} else {
+		function["Role"] = map[string]interface{}{"Fn::GetAtt": []interface{}{roleName, "Arn"}}
+		managedPolicies = append(managedPolicies, samManagedPolicyARN("service-role/AWSLambdaBasicExecutionRole"))
+		if p["Tracing"] == "Active" {
+			managedPolicies = append(managedPolicies, samManagedPolicyARN("AWSXrayWriteOnlyAccess"))
+		}
+		if p["VpcConfig"] != nil {
+			managedPolicies = append(managedPolicies, samManagedPolicyARN("service-role/AWSLambdaVPCAccessExecutionRole"))
+		}
+		if dlq, ok := p["DeadLetterQueue"].(map[string]interface{}); ok {
+			action := "sqs:SendMessage"
+			if dlq["Type"] == "SNS" {
+				action = "sns:Publish"
+			}
+			inlinePolicies = append(inlinePolicies, map[string]interface{}{
+				"PolicyName":     name + "RolePolicyDeadLetterQueue",
+				"PolicyDocument": samPolicyDocument(map[string]interface{}{"Effect": "Allow", "Action": action, "Resource": dlq["TargetArn"]}),
+			})
+		}
+	}
+	add(name, e.mainResource(name, "AWS::Lambda::Function", function))
+
+	// Event sources and function URLs invoke the alias if there is one
+	target := map[string]interface{}{"Fn::GetAtt": []interface{}{name, "Arn"}}
+	functionName := map[string]interface{}{"Ref": name}
+	subTarget := "${" + name + ".Arn}"
+	if alias, ok := p["AutoPublishAlias"]; ok {
+		version := name + "Version"
+		aliasName := name + "Alias" + samLogicalIDPart(alias)
+		add(version, e.resource(name, "AWS::Lambda::Version", map[string]interface{}{
+			"FunctionName": map[string]interface{}{"Ref": name},
+		}))
+		add(aliasName, e.resource(name, "AWS::Lambda::Alias", map[string]interface{}{
+			"Name":            alias,
+			"FunctionName":    map[string]interface{}{"Ref": name},
+			"FunctionVersion": map[string]interface{}{"Fn::GetAtt": []interface{}{version, "Version"}},
+		}))
+		target = map[string]interface{}{"Ref": aliasName}
+		functionName = target
+		subTarget = "${" + aliasName + "}"
+	}
+
+	if url, ok := p["FunctionUrlConfig"].(map[string]interface{}); ok {
+		properties := map[string]interface{}{"TargetFunctionArn": functionName}
+		copySAMProperties(properties, url, "AuthType", "Cors", "InvokeMode")
+		add(name+"Url", e.resource(name, "AWS::Lambda::Url", properties))
+		if url["AuthType"] == "NONE" {
+			add(name+"UrlPublicPermissions", e.resource(name, "AWS::Lambda::Permission", map[string]interface{}{
+				"Action":              "lambda:InvokeFunctionUrl",
+				"FunctionName":        functionName,
+				"FunctionUrlAuthType": "NONE",
+				"Principal":           "*",
+			}))
+		}
+	}
+
+	permission := func(id string, principal string, source map[string]interface{}) {
+		properties := map[string]interface{}{
+			"Action":       "lambda:InvokeFunction",
+			"FunctionName": functionName,
+			"Principal":    principal,
+		}
+		for k, v := range source {
+			properties[k] = v
+		}
+		add(id, e.resource(name, "AWS::Lambda::Permission", properties))
+	}
+
+	events, _ := p["Events"].(map[string]interface{})
+	for _, eventName := range sortedKeys(events) {
+		event, _ := events[eventName].(map[string]interface{})
+		eventType, _ := event["Type"].(string)
+		properties, _ := event["Properties"].(map[string]interface{})
+		id := name + eventName
+
+		switch eventType {
+		case "Api", "HttpApi":
+			apiID, stage := samImplicitRestAPI, "*"
+			key := "RestApiId"
+			if eventType == "HttpApi" {
+				apiID, key = samImplicitHTTPAPI, "ApiId"
+			}
+			if ref, ok := properties[key].(map[string]interface{}); ok && ref["Ref"] != nil {
+				apiID, _ = ref["Ref"].(string)
+			} else if properties[key] != nil {
+				apiID = ""
+			} else if _, ok := e.implicitAPIs[apiID]; !ok {
+				e.implicitAPIs[apiID] = name
+			}
+
+			path, _ := properties["Path"].(string)
+			method, _ := properties["Method"].(string)
+			arnPath := "*"
+			if eventType == "Api" || path != "" {
+				arnMethod := strings.ToUpper(method)
+				if arnMethod == "ANY" || arnMethod == "" {
+					arnMethod = "*"
+				}
+				arnPath = arnMethod + samPathParameterRegex.ReplaceAllString(path, "*")
+			}
+			if apiID != "" {
+				e.addAPIPath(apiID, eventType, path, method, subTarget)
+			}
+
+			api := properties[key]
+			if apiID != "" {
+				api = map[string]interface{}{"Ref": apiID}
+			}
+			permissionID := id + "Permission"
+			if eventType == "Api" {
+				permissionID += "Prod"
+			}
+			permission(permissionID, "apigateway.amazonaws.com", map[string]interface{}{
+				"SourceArn": map[string]interface{}{"Fn::Sub": []interface{}{
+					"arn:${AWS::Partition}:execute-api:${AWS::Region}:${AWS::AccountId}:${__ApiId__}/${__Stage__}/" + strings.TrimPrefix(arnPath, "/"),
+					map[string]interface{}{"__ApiId__": api, "__Stage__": stage},
+				}},
+			})
+
+		case "SQS", "Kinesis", "DynamoDB", "MSK":
+			mapping := map[string]interface{}{"FunctionName": functionName}
+			for k, v := range properties {
+				switch k {
+				case "Queue", "Stream":
+					mapping["EventSourceArn"] = v
+				default:
+					mapping[k] = v
+				}
+			}
+			add(id, e.resource(name, "AWS::Lambda::EventSourceMapping", mapping))
+			policies := map[string]string{
+				"SQS":      "service-role/AWSLambdaSQSQueueExecutionRole",
+				"Kinesis":  "service-role/AWSLambdaKinesisExecutionRole",
+				"DynamoDB": "service-role/AWSLambdaDynamoDBExecutionRole",
+				"MSK":      "service-role/AWSLambdaMSKExecutionRole",
+			}
+			managedPolicies = append(managedPolicies, samManagedPolicyARN(policies[eventType]))
+
+		case "SNS":
+			subscription := map[string]interface{}{
+				"Endpoint": target,
+				"Protocol": "lambda",
+				"TopicArn": properties["Topic"],
+			}
+			copySAMProperties(subscription, properties, "FilterPolicy", "FilterPolicyScope", "Region", "RedrivePolicy")
+			add(id, e.resource(name, "AWS::SNS::Subscription", subscription))
+			permission(id+"Permission", "sns.amazonaws.com", map[string]interface{}{"SourceArn": properties["Topic"]})
+
+		case "S3":
+			permission(id+"Permission", "s3.amazonaws.com", map[string]interface{}{
+				"SourceAccount": map[string]interface{}{"Ref": "AWS::AccountId"},
+			})
+
+		case "Schedule", "CloudWatchEvent", "EventBridgeRule":
+			rule := map[string]interface{}{
+				"Targets": []interface{}{samEventTarget(id, target, properties)},
+			}
+			copySAMProperties(rule, properties, "Description", "EventBusName", "Name", "RoleArn", "State")
+			if schedule, ok := properties["Schedule"]; ok {
+				rule["ScheduleExpression"] = schedule
+			}
+			if pattern, ok := properties["Pattern"]; ok {
+				rule["EventPattern"] = pattern
+			}
+			// Enabled is read in the same way as the enabled column of
+			// awscfn_sam_event. An intrinsic function, e.g. a Ref to a
+			// parameter, is kept as the State so it is still evaluated.
+			if enabled := boolAttribute(properties["Enabled"]); enabled != nil {
+				rule["State"] = map[bool]string{true: "ENABLED", false: "DISABLED"}[*enabled]
+			} else if enabled, ok := properties["Enabled"].(map[string]interface{}); ok {
+				rule["State"] = enabled
+			}
+			add(id, e.resource(name, "AWS::Events::Rule", rule))
+			permission(id+"Permission", "events.amazonaws.com", map[string]interface{}{
+				"SourceArn": map[string]interface{}{"Fn::GetAtt": []interface{}{id, "Arn"}},
+			})
+
+		case "CloudWatchLogs":
+			filter := map[string]interface{}{"DestinationArn": target}
+			copySAMProperties(filter, properties, "FilterPattern", "LogGroupName")
+			add(id, e.resource(name, "AWS::Logs::SubscriptionFilter", filter))
+			permission(id+"Permission", "logs.amazonaws.com", map[string]interface{}{
+				"SourceArn": map[string]interface{}{"Fn::Sub": []interface{}{
+					"arn:${AWS::Partition}:logs:${AWS::Region}:${AWS::AccountId}:log-group:${__LogGroupName__}:*",
+					map[string]interface{}{"__LogGroupName__": properties["LogGroupName"]},
+				}},
+			})
+		}
+	}
+
+	if roleName != "" {
+		managed, inline := samPolicies(name+"RolePolicy", p["Policies"])
+		role := map[string]interface{}{
+			"AssumeRolePolicyDocument": samAssumeRolePolicy("lambda.amazonaws.com"),
+			"ManagedPolicyArns":        append(managedPolicies, managed...),
+			"Tags":                     tags,
+		}
+		if document, ok := p["AssumeRolePolicyDocument"]; ok {
+			role["AssumeRolePolicyDocument"] = document
+		}
+		if policies := append(inlinePolicies, inline...); len(policies) > 0 {
+			role["Policies"] = policies
+		}
+		copySAMProperties(role, p, "PermissionsBoundary")
+		if path, ok := p["RolePath"]; ok {
+			role["Path"] = path
+		}
+		// The role is declared before the function, as in the SAM output
+		resources = append([]resourceDefinition{{Name: roleName, Data: e.resource(name, "AWS::IAM::Role", role), ExpandedFrom: name}}, resources...)
+	}
+
+	return resources
+}
+
+// addAPIPath adds the integration for an Api or HttpApi event to the
+// definition of the API
+func (e *samExpander) addAPIPath(apiID, eventType, path, method, target string) {
+	if e.apiPaths[apiID] == nil {
+		e.apiPaths[apiID] = map[string]interface{}{}
+	}
+	integration := map[string]interface{}{
+		"httpMethod": "POST",
+		"type":       "aws_proxy",
+		"uri":        map[string]interface{}{"Fn::Sub": "arn:${AWS::Partition}:apigateway:${AWS::Region}:lambda:path/2015-03-31/functions/" + target + "/invocations"},
+	}
+	if eventType == "HttpApi" {
+		integration["payloadFormatVersion"] = "2.0"
+		if path == "" {
+			path = "$default"
+		}
+	}
+	method = strings.ToLower(method)
+	if method == "any" || method == "" {
+		method = "x-amazon-apigateway-any-method"
+	}
+
+	methods, ok := e.apiPaths[apiID][path].(map[string]interface{})
+	if !ok {
+		methods = map[string]interface{}{}
+		e.apiPaths[apiID][path] = methods
+	}
+	methods[method] = map[string]interface{}{
+		"x-amazon-apigateway-integration": integration,
+		"responses":                       map[string]interface{}{},
+	}
+}
+
+func (e *samExpander) expandRestAPI(name string, p map[string]interface{}) []resourceDefinition {
+	resources := e.restAPIResources(name, p)
+	for i := range resources {
+		resources[i].ExpandedFrom = name
+	}
+	return resources
+}
+
+// restAPIResources returns the AWS::ApiGateway::RestApi, deployment and stage
+// for an AWS::Serverless::Api, or the implicit API
+func (e *samExpander) restAPIResources(name string, p map[string]interface{}) []resourceDefinition {
+	api := map[string]interface{}{}
+	copySAMProperties(api, p, "ApiKeySourceType", "BinaryMediaTypes", "Description", "DisableExecuteApiEndpoint",
+		"FailOnWarnings", "MinimumCompressionSize", "Mode", "Name")
+	switch {
+	case p["DefinitionBody"] != nil:
+		api["Body"] = p["DefinitionBody"]
+	case p["DefinitionUri"] != nil:
+		api["BodyS3Location"] = samS3Location(p["DefinitionUri"], "Bucket", "Key", "Version")
+	default:
+		api["Body"] = map[string]interface{}{
+			"swagger": "2.0",
+			"info":    map[string]interface{}{"version": "1.0", "title": map[string]interface{}{"Ref": "AWS::StackName"}},
+			"paths":   e.paths(name),
+		}
+	}
+	switch endpoint := p["EndpointConfiguration"].(type) {
+	case string:
+		api["EndpointConfiguration"] = map[string]interface{}{"Types": []interface{}{endpoint}}
+	case map[string]interface{}:
+		configuration := map[string]interface{}{}
+		if endpointType, ok := endpoint["Type"]; ok {
+			configuration["Types"] = []interface{}{endpointType}
+		}
+		copySAMProperties(configuration, endpoint, "VPCEndpointIds")
+		api["EndpointConfiguration"] = configuration
+	}
+	if tags, ok := p["Tags"]; ok {
+		api["Tags"] = samTags(tags, "")
+	}
+
+	deployment := name + "Deployment"
+	stageName := p["StageName"]
+	stageID := name + "Stage"
+	if s, ok := stageName.(string); ok {
+		stageID = name + samLogicalIDPart(s) + "Stage"
+	}
+	stage := map[string]interface{}{
+		"RestApiId":    map[string]interface{}{"Ref": name},
+		"DeploymentId": map[string]interface{}{"Ref": deployment},
+		"StageName":    stageName,
+	}
+	copySAMProperties(stage, p, "AccessLogSetting", "CacheClusterEnabled", "CacheClusterSize", "CanarySetting",
+		"MethodSettings", "TracingEnabled", "Variables")
+	if tags, ok := p["Tags"]; ok {
+		stage["Tags"] = samTags(tags, "")
+	}
+
+	return []resourceDefinition{
+		{Name: name, Data: e.mainResource(name, "AWS::ApiGateway::RestApi", api)},
+		{Name: deployment, Data: e.resource(name, "AWS::ApiGateway::Deployment", map[string]interface{}{
+			"RestApiId": map[string]interface{}{"Ref": name},
+		})},
+		{Name: stageID, Data: e.resource(name, "AWS::ApiGateway::Stage", stage)},
+	}
+}
+
+func (e *samExpander) expandHTTPAPI(name string, p map[string]interface{}) []resourceDefinition {
+	resources := e.httpAPIResources(name, p)
+	for i := range resources {
+		resources[i].ExpandedFrom = name
+	}
+	return resources
+}
+
+// httpAPIResources returns the AWS::ApiGatewayV2::Api and stage for an
+// AWS::Serverless::HttpApi, or the implicit HTTP API
+func (e *samExpander) httpAPIResources(name string, p map[string]interface{}) []resourceDefinition {
+	api := map[string]interface{}{}
+	copySAMProperties(api, p, "Description", "DisableExecuteApiEndpoint", "FailOnWarnings", "Name")
+	switch {
+	case p["DefinitionBody"] != nil:
+		api["Body"] = p["DefinitionBody"]
+	case p["DefinitionUri"] != nil:
+		api["BodyS3Location"] = samS3Location(p["DefinitionUri"], "Bucket", "Key", "Version")
+	default:
+		api["Body"] = map[string]interface{}{
+			"openapi": "3.0.1",
+			"info":    map[string]interface{}{"version": "1.0", "title": map[string]interface{}{"Ref": "AWS::StackName"}},
+			"paths":   e.paths(name),
+			"tags":    []interface{}{map[string]interface{}{"name": "httpapi:createdBy", "x-amazon-apigateway-tag-value": "SAM"}},
+		}
+	}
+	if tags, ok := p["Tags"]; ok {
+		api["Tags"] = tags
+	}
+
+	stageName := p["StageName"]
+	stageID := name + "ApiGatewayDefaultStage"
+	if stageName == nil || stageName == "$default" {
+		stageName = "$default"
+	} else if s, ok := stageName.(string); ok {
+		stageID = name + samLogicalIDPart(s) + "Stage"
+	} else {
+		stageID = name + "Stage"
+	}
+	stage := map[string]interface{}{
+		"ApiId":      map[string]interface{}{"Ref": name},
+		"StageName":  stageName,
+		"AutoDeploy": true,
+	}
+	copySAMProperties(stage, p, "AccessLogSettings", "DefaultRouteSettings", "RouteSettings", "StageVariables")
+
+	return []resourceDefinition{
+		{Name: name, Data: e.mainResource(name, "AWS::ApiGatewayV2::Api", api)},
+		{Name: stageID, Data: e.resource(name, "AWS::ApiGatewayV2::Stage", stage)},
+	}
+}
+
+func (e *samExpander) paths(apiID string) map[string]interface{} {
+	if paths, ok := e.apiPaths[apiID]; ok {
+		return paths
+	}
+	return map[string]interface{}{}
+}
+
+// expandSimpleTable expands an AWS::Serverless::SimpleTable into an
+// AWS::DynamoDB::Table with a single string or number partition key
+func (e *samExpander) expandSimpleTable(name string, p map[string]interface{}) []resourceDefinition {
+	key := map[string]interface{}{"Name": "id", "Type": "String"}
+	if primaryKey, ok := p["PrimaryKey"].(map[string]interface{}); ok {
+		key = primaryKey
+	}
+	attributeType := key["Type"]
+	switch attributeType {
+	case "String":
+		attributeType = "S"
+	case "Number":
+		attributeType = "N"
+	case "Binary":
+		attributeType = "B"
+	}
+
+	table := map[string]interface{}{
+		"AttributeDefinitions": []interface{}{map[string]interface{}{"AttributeName": key["Name"], "AttributeType": attributeType}},
+		"KeySchema":            []interface{}{map[string]interface{}{"AttributeName": key["Name"], "KeyType": "HASH"}},
+	}
+	if throughput, ok := p["ProvisionedThroughput"]; ok {
+		table["ProvisionedThroughput"] = throughput
+	} else {
+		table["BillingMode"] = "PAY_PER_REQUEST"
+	}
+	copySAMProperties(table, p, "PointInTimeRecoverySpecification", "SSESpecification", "TableName")
+	if tags, ok := p["Tags"]; ok {
+		table["Tags"] = samTags(tags, "")
+	}
+
+	return []resourceDefinition{{Name: name, Data: e.mainResource(name, "AWS::DynamoDB::Table", table), ExpandedFrom: name}}
+}
+
+// expandLayerVersion expands an AWS::Serverless::LayerVersion into an
+// AWS::Lambda::LayerVersion, which is retained on deletion by default
+func (e *samExpander) expandLayerVersion(name string, p map[string]interface{}) []resourceDefinition {
+	layer := map[string]interface{}{}
+	copySAMProperties(layer, p, "CompatibleArchitectures", "CompatibleRuntimes", "Description", "LayerName", "LicenseInfo")
+	if content, ok := p["ContentUri"]; ok {
+		layer["Content"] = samS3Location(content, "S3Bucket", "S3Key", "S3ObjectVersion")
+	}
+
+	data := e.mainResource(name, "AWS::Lambda::LayerVersion", layer)
+	data["DeletionPolicy"] = "Retain"
+	if policy, ok := p["RetentionPolicy"]; ok {
+		data["DeletionPolicy"] = policy
+	}
+
+	return []resourceDefinition{{Name: name, Data: data, ExpandedFrom: name}}
+}
+
+// expandStateMachine expands an AWS::Serverless::StateMachine into an
+// AWS::StepFunctions::StateMachine and its execution role
+func (e *samExpander) expandStateMachine(name string, p map[string]interface{}) []resourceDefinition {
+	var resources []resourceDefinition
+
+	stateMachine := map[string]interface{}{}
+	copySAMProperties(stateMachine, p, "Definition", "DefinitionSubstitutions")
+	if uri, ok := p["DefinitionUri"]; ok {
+		stateMachine["DefinitionS3Location"] = samS3Location(uri, "Bucket", "Key", "Version")
+	}
+	for from, to := range map[string]string{"Logging": "LoggingConfiguration", "Name": "StateMachineName", "Tracing": "TracingConfiguration", "Type": "StateMachineType"} {
+		if v, ok := p[from]; ok {
+			stateMachine[to] = v
+		}
+	}
+	tags := samTags(p["Tags"], "stateMachine:createdBy")
+	stateMachine["Tags"] = tags
+
+	if role, ok := p["Role"]; ok {
+		stateMachine["RoleArn"] = role
+	} else {
+		roleName := name + "Role"
+		stateMachine["RoleArn"] = map[string]interface{}{"Fn::GetAtt": []interface{}{roleName, "Arn"}}
+		managed, inline := samPolicies(name+"RolePolicy", p["Policies"])
+		role := map[string]interface{}{
+			"AssumeRolePolicyDocument": samAssumeRolePolicy("states.amazonaws.com"),
+			"ManagedPolicyArns":        managed,
+			"Tags":                     tags,
+		}
+		if len(inline) > 0 {
+			role["Policies"] = inline
+		}
+		copySAMProperties(role, p, "PermissionsBoundary")
+		if path, ok := p["RolePath"]; ok {
+			role["Path"] = path
+		}
+		resources = append(resources, resourceDefinition{Name: roleName, Data: e.resource(name, "AWS::IAM::Role", role), ExpandedFrom: name})
+	}
+
+	return append(resources, resourceDefinition{Name: name, Data: e.mainResource(name, "AWS::StepFunctions::StateMachine", stateMachine), ExpandedFrom: name})
+}
+
+// expandApplication expands an AWS::Serverless::Application into an
+// AWS::CloudFormation::Stack. Applications in the AWS Serverless Application
+// Repository are kept as declared, since their template URL is only known
+// when the transform runs.
+func (e *samExpander) expandApplication(name string, p map[string]interface{}) []resourceDefinition {
+	if _, ok := p["Location"].(map[string]interface{}); ok {
+		data, _ := e.template.Resources[name].(map[string]interface{})
+		return []resourceDefinition{{Name: name, Data: data}}
+	}
+
+	stack := map[string]interface{}{"TemplateURL": p["Location"]}
+	copySAMProperties(stack, p, "NotificationARNs", "Parameters", "TimeoutInMinutes")
+	if tags, ok := p["Tags"]; ok {
+		stack["Tags"] = samTags(tags, "")
+	}
+	return []resourceDefinition{{Name: name, Data: e.mainResource(name, "AWS::CloudFormation::Stack", stack), ExpandedFrom: name}}
+}
+
+func copySAMProperties(to, from map[string]interface{}, keys ...string) {
+	for _, k := range keys {
+		if v, ok := from[k]; ok {
+			to[k] = v
+		}
+	}
+}
+
+// samS3Location returns the location of an artifact, which may be declared as
+// an s3:// URI or a map with Bucket, Key and Version keys. Local paths are
+// kept as is, since they are replaced by an S3 location when the template is
+// packaged.
+func samS3Location(v interface{}, bucketKey, keyKey, versionKey string) interface{} {
+	switch location := v.(type) {
+	case string:
+		if path, ok := strings.CutPrefix(location, "s3://"); ok {
+			bucket, key, _ := strings.Cut(path, "/")
+			return map[string]interface{}{bucketKey: bucket, keyKey: key}
+		}
+	case map[string]interface{}:
+		if _, _, ok := intrinsicFunction(location); ok {
+			return location
+		}
+		result := map[string]interface{}{bucketKey: location["Bucket"], keyKey: location["Key"]}
+		if version, ok := location["Version"]; ok {
+			result[versionKey] = version
+		}
+		return result
+	}
+	return v
+}
+
+// samTags converts a SAM tag map into a CloudFormation tag list, sorted by
+// key, with the tag SAM adds to identify the resources it creates first
+func samTags(v interface{}, createdByKey string) []interface{} {
+	tags := []interface{}{}
+	if createdByKey != "" {
+		tags = append(tags, map[string]interface{}{"Key": createdByKey, "Value": "SAM"})
+	}
+	m, _ := v.(map[string]interface{})
+	for _, k := range sortedKeys(m) {
+		tags = append(tags, map[string]interface{}{"Key": k, "Value": m[k]})
+	}
+	return tags
+}
+
+func samManagedPolicyARN(name string) interface{} {
+	return map[string]interface{}{"Fn::Sub": "arn:${AWS::Partition}:iam::aws:policy/" + name}
+}
+
+func samPolicyDocument(statements ...interface{}) map[string]interface{} {
+	return map[string]interface{}{"Version": "2012-10-17", "Statement": statements}
+}
+
+func samAssumeRolePolicy(service string) map[string]interface{} {
+	return samPolicyDocument(map[string]interface{}{
+		"Effect":    "Allow",
+		"Action":    []interface{}{"sts:AssumeRole"},
+		"Principal": map[string]interface{}{"Service": []interface{}{service}},
+	})
+}
+
+// samPolicies converts the Policies property of a SAM function or state
+// machine into managed policy ARNs and inline policies. AWS managed policies
+// may be named without their ARN. SAM policy templates, e.g. S3ReadPolicy,
+// are expanded into inline policies. Templates that are not expanded are
+// listed as inline policies with a SAMPolicyTemplate member holding the
+// template name and its arguments, so that the permissions are not lost.
+func samPolicies(prefix string, v interface{}) ([]interface{}, []interface{}) {
+	var policies []interface{}
+	switch p := v.(type) {
+	case []interface{}:
+		policies = p
+	case nil:
+	default:
+		policies = []interface{}{p}
+	}
+
+	managed := []interface{}{}
+	var inline []interface{}
+	for i, policy := range policies {
+		// Inline policies are named after their position in the list
+		policyName := fmt.Sprintf("%s%d", prefix, i)
+		switch value := policy.(type) {
+		case string:
+			if strings.HasPrefix(value, "arn:") {
+				managed = append(managed, value)
+			} else {
+				managed = append(managed, samManagedPolicyARN(value))
+			}
+		case map[string]interface{}:
+			if _, ok := value["Statement"]; ok {
+				inline = append(inline, map[string]interface{}{
+					"PolicyName":     policyName,
+					"PolicyDocument": value,
+				})
+			} else if _, _, ok := intrinsicFunction(value); ok {
+				managed = append(managed, value)
+			} else if len(value) == 1 {
+				for name, args := range value {
+					if document, ok := expandSAMPolicyTemplate(name, args); ok {
+						inline = append(inline, map[string]interface{}{
+							"PolicyName":     policyName,
+							"PolicyDocument": document,
+						})
+					} else {
+						inline = append(inline, map[string]interface{}{
+							"PolicyName":        policyName,
+							"SAMPolicyTemplate": value,
+						})
+					}
+				}
+			}
+		}
+	}
+	return managed, inline
+}
+
+func samEventTarget(id string, target interface{}, properties map[string]interface{}) map[string]interface{} {
+	eventTarget := map[string]interface{}{"Arn": target, "Id": id + "LambdaTarget"}
+	copySAMProperties(eventTarget, properties, "DeadLetterConfig", "Input", "InputPath", "InputTransformer", "RetryPolicy")
+	return eventTarget
+}
+
+var (
+	samPathParameterRegex = regexp.MustCompile(`\{[^}]*\}`)
+	nonAlphanumericRegex  = regexp.MustCompile(`[^A-Za-z0-9]`)
+)
+
+// samLogicalIDPart returns a value, e.g. a stage name, with the characters
+// that are not allowed in logical IDs removed
+func samLogicalIDPart(v interface{}) string {
+	return nonAlphanumericRegex.ReplaceAllString(scalarString(v), "")
+}
+
+// samGlobalProperty is a property declared in the Globals section
+type samGlobalProperty struct {
+	ResourceType string
+	Property     string
+	Value        interface{}
+	Line         int
+}
+
+// samGlobals returns the properties declared in the Globals section, sorted
+// by section and property
+func (t *cfnTemplate) samGlobals() []samGlobalProperty {
+	var properties []samGlobalProperty
+	for _, section := range sortedKeys(t.Globals) {
+		resourceType := samGlobalsResourceTypes[section]
+		if resourceType == "" {
+			resourceType = "AWS::Serverless::" + section
+		}
+		values, _ := t.Globals[section].(map[string]interface{})
+		for _, property := range sortedKeys(values) {
+			line, _ := t.keyPosition("Globals", section, property)
+			properties = append(properties, samGlobalProperty{
+				ResourceType: resourceType,
+				Property:     property,
+				Value:        values[property],
+				Line:         line,
+			})
+		}
+	}
+	return properties
+}
+
+// samEvent is an event source declared in the Events property of an
+// AWS::Serverless::Function
+type samEvent struct {
+	FunctionName string
+	EventName    string
+	EventType    string
+	Properties   interface{}
+	Line         int
+}
+
+// samEvents returns the events of the SAM functions of the template, in the
+// order the functions are declared in the file
+func (t *cfnTemplate) samEvents() []samEvent {
+	var events []samEvent
+	for _, name := range t.resourceNames() {
+		if t.resourceType(name) != "AWS::Serverless::Function" {
+			continue
+		}
+		resource := t.Resources[name].(map[string]interface{})
+		properties, _ := resource["Properties"].(map[string]interface{})
+		functionEvents, _ := properties["Events"].(map[string]interface{})
+		for _, eventName := range sortedKeys(functionEvents) {
+			event, _ := functionEvents[eventName].(map[string]interface{})
+			eventType, _ := event["Type"].(string)
+			line, _ := t.keyPosition("Resources", name, "Properties", "Events", eventName)
+			events = append(events, samEvent{
+				FunctionName: name,
+				EventName:    eventName,
+				EventType:    eventType,
+				Properties:   event["Properties"],
+				Line:         line,
+			})
+		}
+	}
+	return events
+}
@@ -0,0 +1,236 @@
+package awscfn
+
+// samPolicyTemplate is a SAM policy template, e.g. S3ReadPolicy, which SAM
+// replaces with an inline policy. The statements refer to the parameters of
+// the template using Ref, which is replaced by the values passed for them.
+type samPolicyTemplate struct {
+	Parameters []string
+	Statement  []interface{}
+}
+
+// samPolicyTemplates are the most commonly used SAM policy templates, as
+// defined in the policy_templates.json file of the SAM translator
+var samPolicyTemplates = map[string]samPolicyTemplate{
+	"AWSSecretsManagerGetSecretValuePolicy": {
+		Parameters: []string{"SecretArn"},
+		Statement: []interface{}{
+			samPolicyStatement([]string{"secretsmanager:GetSecretValue"}, map[string]interface{}{"Ref": "SecretArn"}),
+		},
+	},
+	"CloudWatchPutMetricPolicy": {
+		Statement: []interface{}{
+			samPolicyStatement([]string{"cloudwatch:PutMetricData"}, "*"),
+		},
+	},
+	"DynamoDBCrudPolicy": {
+		Parameters: []string{"TableName"},
+		Statement: []interface{}{
+			samPolicyStatement(
+				[]string{"dynamodb:GetItem", "dynamodb:DeleteItem", "dynamodb:PutItem", "dynamodb:Scan", "dynamodb:Query", "dynamodb:UpdateItem", "dynamodb:BatchWriteItem", "dynamodb:BatchGetItem", "dynamodb:DescribeTable", "dynamodb:ConditionCheckItem"},
+				samPolicyTemplateARN("arn:${AWS::Partition}:dynamodb:${AWS::Region}:${AWS::AccountId}:table/${tableName}", "tableName", "TableName"),
+				samPolicyTemplateARN("arn:${AWS::Partition}:dynamodb:${AWS::Region}:${AWS::AccountId}:table/${tableName}/index/*", "tableName", "TableName"),
+			),
+		},
+	},
+	"DynamoDBReadPolicy": {
+		Parameters: []string{"TableName"},
+		Statement: []interface{}{
+			samPolicyStatement(
+				[]string{"dynamodb:GetItem", "dynamodb:Scan", "dynamodb:Query", "dynamodb:BatchGetItem", "dynamodb:DescribeTable"},
+				samPolicyTemplateARN("arn:${AWS::Partition}:dynamodb:${AWS::Region}:${AWS::AccountId}:table/${tableName}", "tableName", "TableName"),
+				samPolicyTemplateARN("arn:${AWS::Partition}:dynamodb:${AWS::Region}:${AWS::AccountId}:table/${tableName}/index/*", "tableName", "TableName"),
+			),
+		},
+	},
+	"DynamoDBWritePolicy": {
+		Parameters: []string{"TableName"},
+		Statement: []interface{}{
+			samPolicyStatement(
+				[]string{"dynamodb:PutItem", "dynamodb:UpdateItem", "dynamodb:BatchWriteItem"},
+				samPolicyTemplateARN("arn:${AWS::Partition}:dynamodb:${AWS::Region}:${AWS::AccountId}:table/${tableName}", "tableName", "TableName"),
+				samPolicyTemplateARN("arn:${AWS::Partition}:dynamodb:${AWS::Region}:${AWS::AccountId}:table/${tableName}/index/*", "tableName", "TableName"),
+			),
+		},
+	},
+	"EventBridgePutEventsPolicy": {
+		Parameters: []string{"EventBusName"},
+		Statement: []interface{}{
+			samPolicyStatement(
+				[]string{"events:PutEvents"},
+				samPolicyTemplateARN("arn:${AWS::Partition}:events:${AWS::Region}:${AWS::AccountId}:event-bus/${eventBusName}", "eventBusName", "EventBusName"),
+			),
+		},
+	},
+	"KMSDecryptPolicy": {
+		Parameters: []string{"KeyId"},
+		Statement: []interface{}{
+			samPolicyStatement(
+				[]string{"kms:Decrypt"},
+				samPolicyTemplateARN("arn:${AWS::Partition}:kms:${AWS::Region}:${AWS::AccountId}:key/${keyId}", "keyId", "KeyId"),
+			),
+		},
+	},
+	"KMSEncryptPolicy": {
+		Parameters: []string{"KeyId"},
+		Statement: []interface{}{
+			samPolicyStatement(
+				[]string{"kms:Encrypt"},
+				samPolicyTemplateARN("arn:${AWS::Partition}:kms:${AWS::Region}:${AWS::AccountId}:key/${keyId}", "keyId", "KeyId"),
+			),
+		},
+	},
+	"LambdaInvokePolicy": {
+		Parameters: []string{"FunctionName"},
+		Statement: []interface{}{
+			samPolicyStatement(
+				[]string{"lambda:InvokeFunction"},
+				samPolicyTemplateARN("arn:${AWS::Partition}:lambda:${AWS::Region}:${AWS::AccountId}:function:${functionName}*", "functionName", "FunctionName"),
+			),
+		},
+	},
+	"S3CrudPolicy": {
+		Parameters: []string{"BucketName"},
+		Statement: []interface{}{
+			samPolicyStatement(
+				[]string{"s3:GetObject", "s3:ListBucket", "s3:GetBucketLocation", "s3:GetObjectVersion", "s3:PutObject", "s3:PutObjectAcl", "s3:GetLifecycleConfiguration", "s3:PutLifecycleConfiguration", "s3:DeleteObject"},
+				samPolicyTemplateARN("arn:${AWS::Partition}:s3:::${bucketName}", "bucketName", "BucketName"),
+				samPolicyTemplateARN("arn:${AWS::Partition}:s3:::${bucketName}/*", "bucketName", "BucketName"),
+			),
+		},
+	},
+	"S3ReadPolicy": {
+		Parameters: []string{"BucketName"},
+		Statement: []interface{}{
+			samPolicyStatement(
+				[]string{"s3:GetObject", "s3:ListBucket", "s3:GetBucketLocation", "s3:GetObjectVersion", "s3:GetLifecycleConfiguration"},
+				samPolicyTemplateARN("arn:${AWS::Partition}:s3:::${bucketName}", "bucketName", "BucketName"),
+				samPolicyTemplateARN("arn:${AWS::Partition}:s3:::${bucketName}/*", "bucketName", "BucketName"),
+			),
+		},
+	},
+	"S3WritePolicy": {
+		Parameters: []string{"BucketName"},
+		Statement: []interface{}{
+			samPolicyStatement(
+				[]string{"s3:PutObject", "s3:PutObjectAcl", "s3:PutLifecycleConfiguration"},
+				samPolicyTemplateARN("arn:${AWS::Partition}:s3:::${bucketName}", "bucketName", "BucketName"),
+				samPolicyTemplateARN("arn:${AWS::Partition}:s3:::${bucketName}/*", "bucketName", "BucketName"),
+			),
+		},
+	},
+	"SNSPublishMessagePolicy": {
+		Parameters: []string{"TopicName"},
+		Statement: []interface{}{
+			samPolicyStatement(
+				[]string{"sns:Publish"},
+				samPolicyTemplateARN("arn:${AWS::Partition}:sns:${AWS::Region}:${AWS::AccountId}:${topicName}", "topicName", "TopicName"),
+			),
+		},
+	},
+	"SQSPollerPolicy": {
+		Parameters: []string{"QueueName"},
+		Statement: []interface{}{
+			samPolicyStatement(
+				[]string{"sqs:ChangeMessageVisibility", "sqs:ChangeMessageVisibilityBatch", "sqs:DeleteMessage", "sqs:DeleteMessageBatch", "sqs:GetQueueAttributes", "sqs:ReceiveMessage"},
+				samPolicyTemplateARN("arn:${AWS::Partition}:sqs:${AWS::Region}:${AWS::AccountId}:${queueName}", "queueName", "QueueName"),
+			),
+		},
+	},
+	"SQSSendMessagePolicy": {
+		Parameters: []string{"QueueName"},
+		Statement: []interface{}{
+			samPolicyStatement(
+				[]string{"sqs:SendMessage*"},
+				samPolicyTemplateARN("arn:${AWS::Partition}:sqs:${AWS::Region}:${AWS::AccountId}:${queueName}", "queueName", "QueueName"),
+			),
+		},
+	},
+	"SSMParameterReadPolicy": {
+		Parameters: []string{"ParameterName"},
+		Statement: []interface{}{
+			samPolicyStatement([]string{"ssm:DescribeParameters"}, "*"),
+			samPolicyStatement(
+				[]string{"ssm:GetParameters", "ssm:GetParameter", "ssm:GetParametersByPath"},
+				samPolicyTemplateARN("arn:${AWS::Partition}:ssm:${AWS::Region}:${AWS::AccountId}:parameter/${parameterName}", "parameterName", "ParameterName"),
+			),
+		},
+	},
+	"StepFunctionsExecutionPolicy": {
+		Parameters: []string{"StateMachineName"},
+		Statement: []interface{}{
+			samPolicyStatement(
+				[]string{"states:StartExecution"},
+				samPolicyTemplateARN("arn:${AWS::Partition}:states:${AWS::Region}:${AWS::AccountId}:stateMachine:${stateMachineName}", "stateMachineName", "StateMachineName"),
+			),
+		},
+	},
+	"VPCAccessPolicy": {
+		Statement: []interface{}{
+			samPolicyStatement([]string{"ec2:CreateNetworkInterface", "ec2:DeleteNetworkInterface", "ec2:DescribeNetworkInterfaces", "ec2:DetachNetworkInterface"}, "*"),
+		},
+	},
+}
+
+func samPolicyStatement(actions []string, resources ...interface{}) map[string]interface{} {
+	action := make([]interface{}, len(actions))
+	for i, a := range actions {
+		action[i] = a
+	}
+	var resource interface{} = resources
+	if len(resources) == 1 {
+		resource = resources[0]
+	}
+	return map[string]interface{}{"Effect": "Allow", "Action": action, "Resource": resource}
+}
+
+// samPolicyTemplateARN returns a Fn::Sub of the ARN format, with the variable
+// set to the value of the template parameter
+func samPolicyTemplateARN(format, variable, parameter string) map[string]interface{} {
+	return map[string]interface{}{"Fn::Sub": []interface{}{
+		format,
+		map[string]interface{}{variable: map[string]interface{}{"Ref": parameter}},
+	}}
+}
+
+// expandSAMPolicyTemplate returns the policy document of a policy template
+// with the given arguments, and false if the template is not known or a
+// parameter is not passed
+func expandSAMPolicyTemplate(name string, args interface{}) (map[string]interface{}, bool) {
+	template, ok := samPolicyTemplates[name]
+	if !ok {
+		return nil, false
+	}
+	values, _ := args.(map[string]interface{})
+	for _, parameter := range template.Parameters {
+		if values[parameter] == nil {
+			return nil, false
+		}
+	}
+	statement := substituteSAMPolicyParameters(template.Statement, values)
+	return map[string]interface{}{"Statement": statement}, true
+}
+
+// substituteSAMPolicyParameters returns a copy of the value with each Ref to a
+// template parameter replaced by the value passed for it
+func substituteSAMPolicyParameters(v interface{}, values map[string]interface{}) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		if name, ok := value["Ref"].(string); ok && len(value) == 1 {
+			if parameterValue, ok := values[name]; ok {
+				return parameterValue
+			}
+		}
+		result := make(map[string]interface{}, len(value))
+		for k, item := range value {
+			result[k] = substituteSAMPolicyParameters(item, values)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(value))
+		for i, item := range value {
+			result[i] = substituteSAMPolicyParameters(item, values)
+		}
+		return result
+	}
+	return v
+}
@@ -0,0 +1,103 @@
+package awscfn
+
+import (
+	"context"
+
+	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+)
+
+func tableAWSCFNCondition(ctx context.Context) *plugin.Table {
+	return &plugin.Table{
+		Name:        "awscfn_condition",
+		Description: "CloudFormation condition information.",
+		List: &plugin.ListConfig{
+			Hydrate:    listAWSCloudFormationConditions,
+			KeyColumns: plugin.OptionalColumns([]string{"path"}),
+		},
+		Columns: append([]*plugin.Column{
+			{
+				Name:        "name",
+				Description: "The logical ID of the condition.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "expression",
+				Description: "The intrinsic condition function that defines the condition, e.g. Fn::Equals, Fn::And, Fn::Or or Fn::Not.",
+				Type:        proto.ColumnType_JSON,
+			},
+			{
+				Name:        "start_line",
+				Description: "Starting line number.",
+				Type:        proto.ColumnType_INT,
+			},
+			{
+				Name:        "path",
+				Description: "Path to the file.",
+				Type:        proto.ColumnType_STRING,
+			},
+		}, stackPositionColumns()...),
+	}
+}
+
+type awsCFNCondition struct {
+	stackPosition
+	Name       string
+	Expression interface{}
+	StartLine  int
+	Path       string
+}
+
+func listAWSCloudFormationConditions(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateData) (interface{}, error) {
+	// #1 - Path via qual
+	// If the path was requested through qualifier then match it exactly. Globs
+	// are not supported in this context since the output value for the column
+	// will never match the requested value.
+	//
+	// #2 - Path via glob paths in config
+	var paths []string
+	if d.EqualsQuals["path"] != nil {
+		paths = []string{d.EqualsQuals["path"].GetStringValue()}
+	} else {
+		var err error
+		paths, err = listFilesByPath(ctx, d)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	hierarchy, err := getStackHierarchy(ctx, d)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, path := range paths {
+		template, err := getTemplate(ctx, d, path)
+		if err != nil {
+			if skipInvalidTemplate(ctx, d, err) {
+				continue
+			}
+			return nil, err
+		}
+		rows := template.lineNumbers("Conditions")
+
+		for k, v := range template.Conditions {
+			var lineNo int
+			for _, r := range rows {
+				if r.Name == k {
+					lineNo = r.StartLine
+				}
+			}
+
+			d.StreamListItem(ctx, awsCFNCondition{
+				Name:          k,
+				Expression:    v,
+				StartLine:     lineNo,
+				Path:          path,
+				stackPosition: stackPositionOf(hierarchy, path),
+			})
+		}
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,124 @@
+package awscfn
+
+import (
+	"context"
+
+	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/transform"
+)
+
+func tableAWSCFNDependency(ctx context.Context) *plugin.Table {
+	return &plugin.Table{
+		Name:        "awscfn_dependency",
+		Description: "Explicit and implicit dependencies between the resources of CloudFormation templates.",
+		List: &plugin.ListConfig{
+			Hydrate:    listAWSCloudFormationDependencies,
+			KeyColumns: plugin.OptionalColumns([]string{"path"}),
+		},
+		Columns: append([]*plugin.Column{
+			{
+				Name:        "resource_name",
+				Description: "The logical ID of the resource that has the dependency.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "depends_on_name",
+				Description: "The logical ID of the resource it depends on.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "functions",
+				Description: "The functions or attributes that make the dependency, e.g. [\"DependsOn\", \"Fn::GetAtt\"].",
+				Type:        proto.ColumnType_JSON,
+			},
+			{
+				Name:        "is_explicit",
+				Description: "True if the dependency is declared in the DependsOn attribute of the resource.",
+				Type:        proto.ColumnType_BOOL,
+			},
+			{
+				Name:        "is_implicit",
+				Description: "True if the resource refers to the other resource using Ref, Fn::GetAtt or Fn::Sub.",
+				Type:        proto.ColumnType_BOOL,
+			},
+			{
+				Name:        "in_cycle",
+				Description: "True if the dependency is part of a circular dependency, which CloudFormation rejects.",
+				Type:        proto.ColumnType_BOOL,
+			},
+			{
+				Name:        "creation_order",
+				Description: "The creation order of the resource, i.e. one more than the highest creation order of the resources it depends on. Resources without dependencies have order 0, and resources with the same order can be created in parallel. Null if the resource is in, or depends on, a circular dependency.",
+				Type:        proto.ColumnType_INT,
+				Transform:   transform.FromField("CreationOrder"),
+			},
+			{
+				Name:        "depends_on_creation_order",
+				Description: "The creation order of the resource it depends on.",
+				Type:        proto.ColumnType_INT,
+				Transform:   transform.FromField("DependsOnCreationOrder"),
+			},
+			{
+				Name:        "line",
+				Description: "The line number of the first reference that makes the dependency.",
+				Type:        proto.ColumnType_INT,
+			},
+			{
+				Name:        "path",
+				Description: "Path to the file.",
+				Type:        proto.ColumnType_STRING,
+			},
+		}, stackPositionColumns()...),
+	}
+}
+
+type awsCFNDependency struct {
+	stackPosition
+	resourceDependency
+	Path string
+}
+
+func listAWSCloudFormationDependencies(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateData) (interface{}, error) {
+	// #1 - Path via qual
+	// If the path was requested through qualifier then match it exactly. Globs
+	// are not supported in this context since the output value for the column
+	// will never match the requested value.
+	//
+	// #2 - Path via glob paths in config
+	var paths []string
+	if d.EqualsQuals["path"] != nil {
+		paths = []string{d.EqualsQuals["path"].GetStringValue()}
+	} else {
+		var err error
+		paths, err = listFilesByPath(ctx, d)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	hierarchy, err := getStackHierarchy(ctx, d)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, path := range paths {
+		template, err := getTemplate(ctx, d, path)
+		if err != nil {
+			if skipInvalidTemplate(ctx, d, err) {
+				continue
+			}
+			return nil, err
+		}
+
+		for _, dependency := range template.dependencies() {
+			d.StreamListItem(ctx, awsCFNDependency{
+				resourceDependency: dependency,
+				Path:               path,
+				stackPosition:      stackPositionOf(hierarchy, path),
+			})
+		}
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,116 @@
+package awscfn
+
+import (
+	"context"
+
+	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+)
+
+func tableAWSCFNLintFinding(ctx context.Context) *plugin.Table {
+	return &plugin.Table{
+		Name:        "awscfn_lint_finding",
+		Description: "Undefined references and unused declarations found in CloudFormation templates.",
+		List: &plugin.ListConfig{
+			Hydrate:    listAWSCloudFormationLintFindings,
+			KeyColumns: plugin.OptionalColumns([]string{"path"}),
+		},
+		Columns: append([]*plugin.Column{
+			{
+				Name:        "rule",
+				Description: "The rule that found the problem, i.e. undefined_reference, undefined_resource, undefined_mapping, undefined_mapping_key, undefined_condition, unused_parameter, unused_mapping or unused_condition.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "severity",
+				Description: "The severity of the finding, error for references that CloudFormation rejects when the stack is created or updated, or warning for unused declarations and for undefined references in templates with a Transform, which may declare them.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "message",
+				Description: "A description of the problem.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "section",
+				Description: "The template section of the member the finding is about, i.e. the section that makes an undefined reference, or the section of an unused declaration.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "name",
+				Description: "The logical ID of the member the finding is about.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "property_path",
+				Description: "The path of an undefined reference within its source, e.g. Properties.Tags[0].Value.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "target_name",
+				Description: "The name an undefined reference refers to.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "line",
+				Description: "The line number of the undefined reference or unused declaration.",
+				Type:        proto.ColumnType_INT,
+			},
+			{
+				Name:        "path",
+				Description: "Path to the file.",
+				Type:        proto.ColumnType_STRING,
+			},
+		}, stackPositionColumns()...),
+	}
+}
+
+type awsCFNLintFinding struct {
+	stackPosition
+	lintFinding
+	Path string
+}
+
+func listAWSCloudFormationLintFindings(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateData) (interface{}, error) {
+	// #1 - Path via qual
+	// If the path was requested through qualifier then match it exactly. Globs
+	// are not supported in this context since the output value for the column
+	// will never match the requested value.
+	//
+	// #2 - Path via glob paths in config
+	var paths []string
+	if d.EqualsQuals["path"] != nil {
+		paths = []string{d.EqualsQuals["path"].GetStringValue()}
+	} else {
+		var err error
+		paths, err = listFilesByPath(ctx, d)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	hierarchy, err := getStackHierarchy(ctx, d)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, path := range paths {
+		template, err := getTemplate(ctx, d, path)
+		if err != nil {
+			if skipInvalidTemplate(ctx, d, err) {
+				continue
+			}
+			return nil, err
+		}
+
+		for _, finding := range template.lint() {
+			d.StreamListItem(ctx, awsCFNLintFinding{
+				lintFinding:   finding,
+				Path:          path,
+				stackPosition: stackPositionOf(hierarchy, path),
+			})
+		}
+	}
+
+	return nil, nil
+}
@@ -1,17 +1,15 @@
 package awscfn
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
-	"os"
+	"strconv"
 	"strings"
 
 	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
 	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
 	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/transform"
-	"gopkg.in/yaml.v3"
 )
 
 func tableAWSCFNMapping(ctx context.Context) *plugin.Table {
@@ -22,7 +20,7 @@ func tableAWSCFNMapping(ctx context.Context) *plugin.Table {
 			Hydrate:    listAWSCloudFormationMappings,
 			KeyColumns: plugin.OptionalColumns([]string{"path"}),
 		},
-		Columns: []*plugin.Column{
+		Columns: append([]*plugin.Column{
 			{
 				Name:        "map",
 				Description: "Mapping name.",
@@ -54,11 +52,12 @@ func tableAWSCFNMapping(ctx context.Context) *plugin.Table {
 				Description: "Path to the file.",
 				Type:        proto.ColumnType_STRING,
 			},
-		},
+		}, stackPositionColumns()...),
 	}
 }
 
 type awsCFNMapping struct {
+	stackPosition
 	Map       string
 	Key       string
 	Name      string
@@ -67,11 +66,6 @@ type awsCFNMapping struct {
 	Path      string
 }
 
-type MappingsStruct struct {
-	Mappings  map[string]interface{} `cty:"Mappings"`
-	Resources map[string]interface{} `cty:"Resources"`
-}
-
 func listAWSCloudFormationMappings(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateData) (interface{}, error) {
 	// #1 - Path via qual
 	// If the path was requested through qualifier then match it exactly. Globs
@@ -90,82 +84,22 @@ func listAWSCloudFormationMappings(ctx context.Context, d *plugin.QueryData, h *
 		}
 	}
 
+	hierarchy, err := getStackHierarchy(ctx, d)
+	if err != nil {
+		return nil, err
+	}
+
 	for _, path := range paths {
-		// Read files
-		content, err := os.ReadFile(path)
+		template, err := getTemplate(ctx, d, path)
 		if err != nil {
-			plugin.Logger(ctx).Error("awscfn_mapping.listAWSCloudFormationMappings", "file_error", err, "path", path)
-			return nil, fmt.Errorf("failed to read file %s: %w", path, err)
-		}
-
-		// Parse file contents
-		var body interface{}
-		if err := yaml.Unmarshal(content, &body); err != nil {
-			panic(err)
-		}
-		body = convert(body)
-
-		var result MappingsStruct
-		if b, err := json.Marshal(body); err != nil {
-			panic(err)
-		} else {
-			err = json.Unmarshal(b, &result)
-			if err != nil {
-				plugin.Logger(ctx).Error("awscfn_mapping.listAWSCloudFormationMappings", "parse_error", err, "path", path)
-				return nil, fmt.Errorf("failed to unmarshal file content %s: %w", path, err)
+			if skipInvalidTemplate(ctx, d, err) {
+				continue
 			}
+			return nil, err
 		}
-
-		// Fail if no Resources attribute defined in template file
-		if result.Resources == nil {
-			plugin.Logger(ctx).Error("awscfn_mapping.listAWSCloudFormationMappings", "template_format_error", err, "path", path)
-			return nil, fmt.Errorf("failed to parse AWS CloudFormation template from file %s: Template format error: At least one Resources member must be defined", path)
-		}
-
-		// Decode file contents
-		var root yaml.Node
-		r := bytes.NewReader(content)
-		decoder := yaml.NewDecoder(r)
-		err = decoder.Decode(&root)
-		if err != nil {
-			plugin.Logger(ctx).Error("awscfn_mapping.listAWSCloudFormationMappings", "parse_error", err, "path", path)
-			return nil, fmt.Errorf("failed to parse file: %w", err)
-		}
-		var rows Rows
-		treeToList(&root, []string{}, &rows, "Mappings")
-
-		for k, v := range result.Mappings {
-			for mapKey, mapValue := range v.(map[string]interface{}) {
-				for nameKey, nameValue := range mapValue.(map[string]interface{}) {
-					var lineNo int
-					for _, r := range rows {
-						if strings.HasPrefix(r.Name, "Mappings.") {
-							// Get line number for matching nameKey
-							// Since same nameKey can be defined in different Mappings,
-							// Check mapKey to avoid fetching incorrect line number
-							var compareKey string
-							splitName := strings.Split(r.Name, ".")
-							if len(splitName) == 4 && k == splitName[1] && mapKey == splitName[2] { // i.e. Mappings.RegionExamples.us-east-1.Examples
-								compareKey = splitName[3]
-							} else if len(splitName) == 5 && k == splitName[1] && mapKey == strings.Join(splitName[2:4], ".") { // Handle InstanceType mapping; i.e. Mappings.AWSInstanceType2Arch.t1.micro.Arch
-								compareKey = splitName[4]
-							}
-							if compareKey == nameKey {
-								lineNo = r.StartLine
-							}
-						}
-					}
-
-					d.StreamListItem(ctx, awsCFNMapping{
-						Map:       k,
-						Key:       mapKey,
-						Name:      nameKey,
-						Value:     nameValue,
-						StartLine: lineNo,
-						Path:      path,
-					})
-				}
-			}
+		for _, row := range template.mappingRows() {
+			row.stackPosition = stackPositionOf(hierarchy, path)
+			d.StreamListItem(ctx, row)
 		}
 	}
 
@@ -175,13 +109,67 @@ func listAWSCloudFormationMappings(ctx context.Context, d *plugin.QueryData, h *
 func formatValue(ctx context.Context, d *transform.TransformData) (interface{}, error) {
 	data := d.HydrateItem.(awsCFNMapping)
 	var val string
-	if data.Value != nil {
-		content, isArray := data.Value.([]interface{})
-		if isArray {
-			val = fmt.Sprintf("%v", content)
-		} else {
-			val = data.Value.(string)
+	switch value := data.Value.(type) {
+	case nil:
+	case string:
+		val = value
+	case []interface{}:
+		val = fmt.Sprintf("%v", value)
+	case map[string]interface{}:
+		// Values can be intrinsic functions in templates that use the
+		// AWS::LanguageExtensions transform
+		b, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
 		}
+		val = string(b)
+	case float64:
+		// Avoid exponent notation for large numbers
+		val = strconv.FormatFloat(value, 'f', -1, 64)
+	default:
+		val = fmt.Sprintf("%v", value)
 	}
 	return val, nil
 }
+
+// mappingRows returns the rows of the awscfn_mapping table for the template,
+// i.e. one row per name-value pair of each top level key of each mapping
+func (t *cfnTemplate) mappingRows() []awsCFNMapping {
+	rows := t.lineNumbers("Mappings")
+
+	var mappings []awsCFNMapping
+	for k, v := range t.Mappings {
+		for mapKey, mapValue := range v.(map[string]interface{}) {
+			for nameKey, nameValue := range mapValue.(map[string]interface{}) {
+				var lineNo int
+				for _, r := range rows {
+					if strings.HasPrefix(r.Name, "Mappings.") {
+						// Get line number for matching nameKey
+						// Since same nameKey can be defined in different Mappings,
+						// Check mapKey to avoid fetching incorrect line number
+						var compareKey string
+						splitName := strings.Split(r.Name, ".")
+						if len(splitName) == 4 && k == splitName[1] && mapKey == splitName[2] { // i.e. Mappings.RegionExamples.us-east-1.Examples
+							compareKey = splitName[3]
+						} else if len(splitName) == 5 && k == splitName[1] && mapKey == strings.Join(splitName[2:4], ".") { // Handle InstanceType mapping; i.e. Mappings.AWSInstanceType2Arch.t1.micro.Arch
+							compareKey = splitName[4]
+						}
+						if compareKey == nameKey {
+							lineNo = r.StartLine
+						}
+					}
+				}
+
+				mappings = append(mappings, awsCFNMapping{
+					Map:       k,
+					Key:       mapKey,
+					Name:      nameKey,
+					Value:     nameValue,
+					StartLine: lineNo,
+					Path:      t.Path,
+				})
+			}
+		}
+	}
+	return mappings
+}
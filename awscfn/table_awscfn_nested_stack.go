@@ -0,0 +1,239 @@
+package awscfn
+
+import (
+	"context"
+	"sort"
+
+	filehelpers "github.com/turbot/go-kit/files"
+	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/transform"
+)
+
+// Status of a nested stack parameter
+const (
+	nestedStackParameterOK         = "ok"
+	nestedStackParameterInvalid    = "invalid"
+	nestedStackParameterUndeclared = "undeclared"
+	nestedStackParameterMissing    = "missing"
+	nestedStackParameterDefault    = "default"
+	nestedStackParameterUnchecked  = "unchecked"
+)
+
+func tableAWSCFNNestedStack(ctx context.Context) *plugin.Table {
+	return &plugin.Table{
+		Name:        "awscfn_nested_stack",
+		Description: "Parameters passed by AWS::CloudFormation::Stack resources to the templates of nested stacks.",
+		List: &plugin.ListConfig{
+			Hydrate:    listAWSCloudFormationNestedStacks,
+			KeyColumns: plugin.OptionalColumns([]string{"path"}),
+		},
+		Columns: []*plugin.Column{
+			{
+				Name:        "stack_logical_id",
+				Description: "The logical ID of the AWS::CloudFormation::Stack resource.",
+				Type:        proto.ColumnType_STRING,
+				Transform:   transform.FromField("StackLogicalID"),
+			},
+			{
+				Name:        "template_url",
+				Description: "The TemplateURL property of the stack resource.",
+				Type:        proto.ColumnType_JSON,
+				Transform:   transform.FromField("TemplateURL"),
+			},
+			{
+				Name:        "child_path",
+				Description: "Path to the template of the nested stack, if the TemplateURL is a local or relative path to an existing file. Relative paths are resolved against the directory of the parent template.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "parameter_name",
+				Description: "The name of a parameter that is passed by the stack resource or declared by the child template. Null if neither has parameters.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "value",
+				Description: "The value passed for the parameter in the Parameters property of the stack resource.",
+				Type:        proto.ColumnType_JSON,
+			},
+			{
+				Name:        "is_passed",
+				Description: "True if the stack resource passes a value for the parameter.",
+				Type:        proto.ColumnType_BOOL,
+			},
+			{
+				Name:        "is_declared",
+				Description: "True if the child template declares the parameter. Null if the child template is unknown or can not be parsed.",
+				Type:        proto.ColumnType_BOOL,
+			},
+			{
+				Name:        "child_parameter_type",
+				Description: "The type of the parameter declared in the child template.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "child_default_value",
+				Description: "The default value of the parameter declared in the child template.",
+				Type:        proto.ColumnType_JSON,
+			},
+			{
+				Name:        "status",
+				Description: "The status of the parameter, one of ok, invalid if the value is a literal that violates the constraints of the child parameter, unchecked if the value is passed but can not be fully checked against those constraints, e.g. an intrinsic function or an AllowedPattern that can not be compiled, undeclared if the value is passed but the child template does not declare the parameter, missing if the child parameter has no default and no value is passed, or default if the default value is used. Null if the child template is unknown or can not be parsed.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "validation_errors",
+				Description: "The constraints of the child parameter that a literal value violates, e.g. AllowedValues. Null if the value is not validated, e.g. if it is an intrinsic function.",
+				Type:        proto.ColumnType_JSON,
+			},
+			{
+				Name:        "line",
+				Description: "The line number of the parameter in the Parameters property, or of the stack resource if no value is passed.",
+				Type:        proto.ColumnType_INT,
+			},
+			{
+				Name:        "path",
+				Description: "Path to the parent template.",
+				Type:        proto.ColumnType_STRING,
+			},
+		},
+	}
+}
+
+type awsCFNNestedStack struct {
+	StackLogicalID     string
+	TemplateURL        interface{}
+	ChildPath          string
+	ParameterName      string
+	Value              interface{}
+	IsPassed           bool
+	IsDeclared         *bool
+	ChildParameterType interface{}
+	ChildDefaultValue  interface{}
+	Status             string
+	ValidationErrors   []string
+	Line               int
+	Path               string
+}
+
+func listAWSCloudFormationNestedStacks(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateData) (interface{}, error) {
+	// #1 - Path via qual
+	// If the path was requested through qualifier then match it exactly. Globs
+	// are not supported in this context since the output value for the column
+	// will never match the requested value.
+	//
+	// #2 - Path via glob paths in config
+	var paths []string
+	if d.EqualsQuals["path"] != nil {
+		paths = []string{d.EqualsQuals["path"].GetStringValue()}
+	} else {
+		var err error
+		paths, err = listFilesByPath(ctx, d)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, path := range paths {
+		template, err := getTemplate(ctx, d, path)
+		if err != nil {
+			if skipInvalidTemplate(ctx, d, err) {
+				continue
+			}
+			return nil, err
+		}
+
+		for _, stack := range template.nestedStacks() {
+			// The parameters of the child template are only known if it can be
+			// parsed
+			var childParameters map[string]interface{}
+			var childPath string
+			if stack.TemplatePath != "" && filehelpers.FileExists(stack.TemplatePath) {
+				childPath = stack.TemplatePath
+				if child, err := getTemplate(ctx, d, childPath); err == nil {
+					childParameters = child.Parameters
+					if childParameters == nil {
+						childParameters = map[string]interface{}{}
+					}
+				}
+			}
+
+			var names []string
+			for name := range stack.Parameters {
+				names = append(names, name)
+			}
+			for name := range childParameters {
+				if _, ok := stack.Parameters[name]; !ok {
+					names = append(names, name)
+				}
+			}
+			sort.Strings(names)
+
+			row := awsCFNNestedStack{
+				StackLogicalID: stack.LogicalID,
+				TemplateURL:    stack.TemplateURL,
+				ChildPath:      childPath,
+				Line:           stack.Line,
+				Path:           path,
+			}
+
+			// Stacks without parameters are still listed
+			if len(names) == 0 {
+				d.StreamListItem(ctx, row)
+				continue
+			}
+
+			for _, name := range names {
+				parameterRow := row
+				parameterRow.ParameterName = name
+				parameterRow.Value, parameterRow.IsPassed = stack.Parameters[name]
+				if parameterRow.IsPassed {
+					parameterRow.Line, _ = template.keyPosition("Resources", stack.LogicalID, "Properties", "Parameters", name)
+				}
+				if childParameters != nil {
+					setNestedStackParameterStatus(&parameterRow, childParameters[name])
+				}
+				d.StreamListItem(ctx, parameterRow)
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+// setNestedStackParameterStatus compares the value passed for a parameter with
+// its declaration in the child template, which is nil if it is not declared
+func setNestedStackParameterStatus(row *awsCFNNestedStack, declaration interface{}) {
+	parameter, isDeclared := declaration.(map[string]interface{})
+	row.IsDeclared = &isDeclared
+
+	switch {
+	case !isDeclared:
+		row.Status = nestedStackParameterUndeclared
+		return
+	case !row.IsPassed && parameter["Default"] != nil:
+		row.Status = nestedStackParameterDefault
+	case !row.IsPassed:
+		row.Status = nestedStackParameterMissing
+	default:
+		row.Status = nestedStackParameterOK
+	}
+	row.ChildParameterType = parameter["Type"]
+	row.ChildDefaultValue = parameter["Default"]
+
+	// Only literal values can be validated before deployment
+	if !row.IsPassed {
+		return
+	}
+	if !isScalar(row.Value) {
+		row.Status = nestedStackParameterUnchecked
+		return
+	}
+	var complete bool
+	row.ValidationErrors, complete = validateParameterValue(parameter, row.Value)
+	if len(row.ValidationErrors) > 0 {
+		row.Status = nestedStackParameterInvalid
+	} else if !complete {
+		row.Status = nestedStackParameterUnchecked
+	}
+}
@@ -1,15 +1,10 @@
 package awscfn
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
-	"fmt"
-	"os"
 
 	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
 	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
-	"gopkg.in/yaml.v3"
 )
 
 func tableAWSCFNOutput(ctx context.Context) *plugin.Table {
@@ -18,9 +13,9 @@ func tableAWSCFNOutput(ctx context.Context) *plugin.Table {
 		Description: "CloudFormation resource information",
 		List: &plugin.ListConfig{
 			Hydrate:    listAWSCloudFormationOutputs,
-			KeyColumns: plugin.OptionalColumns([]string{"path"}),
+			KeyColumns: plugin.OptionalColumns([]string{"path", "profile"}),
 		},
-		Columns: []*plugin.Column{
+		Columns: append([]*plugin.Column{
 			{
 				Name:        "name",
 				Description: "An identifier for the current output.",
@@ -31,6 +26,11 @@ func tableAWSCFNOutput(ctx context.Context) *plugin.Table {
 				Description: "The value of the property returned by the aws cloudformation describe-stacks command. The value of an output can include literals, parameter references, pseudo-parameters, a mapping value, or intrinsic functions.",
 				Type:        proto.ColumnType_STRING,
 			},
+			{
+				Name:        "value_resolved",
+				Description: "The value of the output with intrinsic functions evaluated using the configured parameter values or defaults, mappings, conditions and pseudo parameters. Functions that cannot be evaluated, e.g. Fn::GetAtt, are left intact.",
+				Type:        proto.ColumnType_JSON,
+			},
 			{
 				Name:        "description",
 				Description: "A String type that describes the output value. The value for the description declaration must be a literal string that's between 0 and 1024 bytes in length. You can't use a parameter or function to specify the description. The description can be a maximum of 4 K in length.",
@@ -41,6 +41,11 @@ func tableAWSCFNOutput(ctx context.Context) *plugin.Table {
 				Description: "The name of the resource output to be exported for a cross-stack reference.",
 				Type:        proto.ColumnType_JSON,
 			},
+			{
+				Name:        "profile",
+				Description: "The name of the profile in the connection config used to evaluate the value, or null if the connection values are used.",
+				Type:        proto.ColumnType_STRING,
+			},
 			{
 				Name:        "start_line",
 				Description: "Starting line number.",
@@ -51,22 +56,20 @@ func tableAWSCFNOutput(ctx context.Context) *plugin.Table {
 				Description: "Path to the file.",
 				Type:        proto.ColumnType_STRING,
 			},
-		},
+		}, stackPositionColumns()...),
 	}
 }
 
 type awsCFNOutput struct {
-	Name        string
-	Value       interface{}
-	Description interface{}
-	Export      interface{}
-	StartLine   int
-	Path        string
-}
-
-type OutputStruct struct {
-	Outputs   map[string]interface{} `cty:"Outputs"`
-	Resources map[string]interface{} `cty:"Resources"`
+	stackPosition
+	Name          string
+	Value         interface{}
+	ValueResolved interface{}
+	Description   interface{}
+	Export        interface{}
+	Profile       string
+	StartLine     int
+	Path          string
 }
 
 func listAWSCloudFormationOutputs(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateData) (interface{}, error) {
@@ -87,76 +90,64 @@ func listAWSCloudFormationOutputs(ctx context.Context, d *plugin.QueryData, h *p
 		}
 	}
 
-	for _, path := range paths {
-		// Read files
-		content, err := os.ReadFile(path)
-		if err != nil {
-			plugin.Logger(ctx).Error("awscfn_output.listAWSCloudFormationOutputs", "file_error", err, "path", path)
-			return nil, fmt.Errorf("failed to read file %s: %w", path, err)
-		}
+	hierarchy, err := getStackHierarchy(ctx, d)
+	if err != nil {
+		return nil, err
+	}
 
-		// Parse file contents
-		var body interface{}
-		if err := yaml.Unmarshal(content, &IncludeProcessor{&body}); err != nil {
-			panic(err)
-		}
-		body = convert(body)
+	values, err := getEvaluationValues(ctx, d)
+	if err != nil {
+		return nil, err
+	}
 
-		var result OutputStruct
-		if b, err := json.Marshal(body); err != nil {
-			panic(err)
-		} else {
-			err = json.Unmarshal(b, &result)
-			if err != nil {
-				plugin.Logger(ctx).Error("awscfn_output.listAWSCloudFormationOutputs", "parse_error", err, "path", path)
-				return nil, fmt.Errorf("failed to unmarshal file content %s: %w", path, err)
+	for _, path := range paths {
+		template, err := getTemplate(ctx, d, path)
+		if err != nil {
+			if skipInvalidTemplate(ctx, d, err) {
+				continue
 			}
+			return nil, err
 		}
 
-		// Fail if no Resources attribute defined in template file
-		if result.Resources == nil {
-			plugin.Logger(ctx).Error("awscfn_output.listAWSCloudFormationOutputs", "template_format_error", err, "path", path)
-			return nil, fmt.Errorf("failed to parse AWS CloudFormation template from file %s: Template format error: At least one Resources member must be defined", path)
+		// Each requested profile gets its own set of rows
+		for _, profileValues := range values {
+			for _, row := range template.outputRows(profileValues) {
+				row.stackPosition = stackPositionOf(hierarchy, path)
+				d.StreamListItem(ctx, row)
+			}
 		}
+	}
 
-		// Decode file contents
-		var root yaml.Node
-		r := bytes.NewReader(content)
-		decoder := yaml.NewDecoder(r)
-		err = decoder.Decode(&root)
-		if err != nil {
-			plugin.Logger(ctx).Error("awscfn_output.listAWSCloudFormationOutputs", "parse_error", err, "path", path)
-			return nil, fmt.Errorf("failed to parse file: %w", err)
-		}
-		var rows Rows
-		treeToList(&root, []string{}, &rows, "Outputs")
+	return nil, nil
+}
 
-		for k, v := range result.Outputs {
-			data := v.(map[string]interface{})
+// outputRows returns the rows of the awscfn_output table for the template,
+// with values evaluated using the given values
+func (t *cfnTemplate) outputRows(values *evaluationValues) []awsCFNOutput {
+	rows := t.lineNumbers("Outputs")
+	evaluator := newTemplateEvaluator(t, values)
 
-			// Return error, if Outputs map has missing Value defined
-			if data["Value"] == nil {
-				plugin.Logger(ctx).Error("awscfn_output.listAWSCloudFormationOutputs", "template_format_error", err, "path", path)
-				return nil, fmt.Errorf("failed to parse AWS CloudFormation template from file %s: Template format error: Every Outputs member must contain a Value object with non-null value", path)
-			}
+	var outputs []awsCFNOutput
+	for k, v := range t.Outputs {
+		data := v.(map[string]interface{})
 
-			var lineNo int
-			for _, r := range rows {
-				if r.Name == k {
-					lineNo = r.StartLine
-				}
+		var lineNo int
+		for _, r := range rows {
+			if r.Name == k {
+				lineNo = r.StartLine
 			}
-
-			d.StreamListItem(ctx, awsCFNOutput{
-				Name:        k,
-				Value:       data["Value"],
-				Description: data["Description"],
-				Export:      data["Export"],
-				StartLine:   lineNo,
-				Path:        path,
-			})
 		}
-	}
 
-	return nil, nil
+		outputs = append(outputs, awsCFNOutput{
+			Name:          k,
+			Value:         data["Value"],
+			ValueResolved: evaluator.evaluate(data["Value"]),
+			Description:   data["Description"],
+			Export:        data["Export"],
+			Profile:       values.Profile,
+			StartLine:     lineNo,
+			Path:          t.Path,
+		})
+	}
+	return outputs
 }
@@ -1,15 +1,15 @@
 package awscfn
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
-	"fmt"
-	"os"
+	"strconv"
+	"strings"
 
+	"github.com/turbot/go-kit/types"
 	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
 	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
-	"gopkg.in/yaml.v3"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/transform"
 )
 
 func tableAWSCFNParameter(ctx context.Context) *plugin.Table {
@@ -20,7 +20,7 @@ func tableAWSCFNParameter(ctx context.Context) *plugin.Table {
 			Hydrate:    listAWSCloudFormationParameters,
 			KeyColumns: plugin.OptionalColumns([]string{"path"}),
 		},
-		Columns: []*plugin.Column{
+		Columns: append([]*plugin.Column{
 			{
 				Name:        "name",
 				Description: "Parameter name.",
@@ -31,30 +31,71 @@ func tableAWSCFNParameter(ctx context.Context) *plugin.Table {
 				Description: "The data type for the parameter.",
 				Type:        proto.ColumnType_STRING,
 			},
+			{
+				Name:        "base_type",
+				Description: "The type of the parameter value, or of each item for list types, i.e. String or Number. AWS-specific parameter types are strings.",
+				Type:        proto.ColumnType_STRING,
+				Transform:   transform.FromField("ParsedType.BaseType"),
+			},
+			{
+				Name:        "is_list",
+				Description: "True if the parameter value is a list, i.e. for CommaDelimitedList and List<...> types, and SSM parameter types with a list value type.",
+				Type:        proto.ColumnType_BOOL,
+				Transform:   transform.FromField("ParsedType.IsList"),
+			},
+			{
+				Name:        "is_ssm",
+				Description: "True if the parameter is an SSM parameter type, i.e. AWS::SSM::Parameter::Name or AWS::SSM::Parameter::Value<...>.",
+				Type:        proto.ColumnType_BOOL,
+				Transform:   transform.FromField("ParsedType.IsSSM"),
+			},
+			{
+				Name:        "ssm_value_type",
+				Description: "The value type of an AWS::SSM::Parameter::Value<...> parameter, e.g. String or List<AWS::EC2::Subnet::Id>.",
+				Type:        proto.ColumnType_STRING,
+				Transform:   transform.FromField("ParsedType.SSMValueType").NullIfZero(),
+			},
+			{
+				Name:        "aws_resource_type",
+				Description: "The AWS-specific parameter type, with any SSM and list wrappers removed, e.g. AWS::EC2::Subnet::Id for List<AWS::EC2::Subnet::Id>.",
+				Type:        proto.ColumnType_STRING,
+				Transform:   transform.FromField("ParsedType.AWSResourceType").NullIfZero(),
+			},
 			{
 				Name:        "default_value",
-				Description: "A value of the appropriate type for the template to use if no value is specified when a stack is created. If you define constraints for the parameter, you must specify a value that adheres to those constraints.",
+				Description: "A value of the appropriate type for the template to use if no value is specified when a stack is created. If you define constraints for the parameter, you must specify a value that adheres to those constraints. Numbers and booleans are rendered as strings, and lists as comma-delimited strings.",
 				Type:        proto.ColumnType_STRING,
+				Transform:   transform.FromField("DefaultValue"),
+			},
+			{
+				Name:        "default_value_json",
+				Description: "The default value as declared in the template, e.g. a number, string, boolean or list.",
+				Type:        proto.ColumnType_JSON,
+				Transform:   transform.FromField("DefaultValueJSON"),
 			},
 			{
 				Name:        "max_length",
 				Description: "An integer value that determines the largest number of characters you want to allow for String types.",
 				Type:        proto.ColumnType_INT,
+				Transform:   transform.FromField("MaxLength"),
 			},
 			{
 				Name:        "min_length",
 				Description: "An integer value that determines the smallest number of characters you want to allow for String types.",
 				Type:        proto.ColumnType_INT,
+				Transform:   transform.FromField("MinLength"),
 			},
 			{
 				Name:        "max_value",
 				Description: "A numeric value that determines the largest numeric value you want to allow for Number types.",
-				Type:        proto.ColumnType_INT,
+				Type:        proto.ColumnType_DOUBLE,
+				Transform:   transform.FromField("MaxValue"),
 			},
 			{
 				Name:        "min_value",
 				Description: "A numeric value that determines the smallest numeric value you want to allow for Number types.",
-				Type:        proto.ColumnType_INT,
+				Type:        proto.ColumnType_DOUBLE,
+				Transform:   transform.FromField("MinValue"),
 			},
 			{
 				Name:        "no_echo",
@@ -81,6 +122,17 @@ func tableAWSCFNParameter(ctx context.Context) *plugin.Table {
 				Description: "A string that explains a constraint when the constraint is violated.",
 				Type:        proto.ColumnType_STRING,
 			},
+			{
+				Name:        "default_is_valid",
+				Description: "True if the default value satisfies the constraints declared for the parameter, i.e. allowed_pattern, allowed_values, min_length, max_length, min_value and max_value. Null if no default value is declared, or if the default satisfies the other constraints but the allowed_pattern uses Java regular expression features that can not be checked, e.g. lookaheads.",
+				Type:        proto.ColumnType_BOOL,
+			},
+			{
+				Name:        "validation_errors",
+				Description: "The constraints that the default value violates. Null if no default value is declared.",
+				Type:        proto.ColumnType_JSON,
+				Transform:   transform.FromField("ValidationErrors"),
+			},
 			{
 				Name:        "start_line",
 				Description: "Starting line number.",
@@ -91,32 +143,32 @@ func tableAWSCFNParameter(ctx context.Context) *plugin.Table {
 				Description: "Path to the file.",
 				Type:        proto.ColumnType_STRING,
 			},
-		},
+		}, stackPositionColumns()...),
 	}
 }
 
 type awsCFNParameter struct {
+	stackPosition
 	Name                  string
 	Type                  string
-	DefaultValue          interface{}
+	ParsedType            parameterType
+	DefaultValue          *string
+	DefaultValueJSON      interface{}
 	Description           interface{}
 	AllowedPattern        interface{}
 	AllowedValues         interface{}
 	ConstraintDescription interface{}
-	MaxLength             interface{}
-	MinLength             interface{}
-	MaxValue              interface{}
-	MinValue              interface{}
-	NoEcho                interface{}
+	MaxLength             *int64
+	MinLength             *int64
+	MaxValue              *float64
+	MinValue              *float64
+	NoEcho                *bool
+	DefaultIsValid        *bool
+	ValidationErrors      []string
 	StartLine             int
 	Path                  string
 }
 
-type parametersStruct struct {
-	Parameters map[string]interface{} `cty:"Parameters"`
-	Resources  map[string]interface{} `cty:"Resources"`
-}
-
 func listAWSCloudFormationParameters(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateData) (interface{}, error) {
 	// #1 - Path via qual
 	// If the path was requested through qualifier then match it exactly. Globs
@@ -135,83 +187,136 @@ func listAWSCloudFormationParameters(ctx context.Context, d *plugin.QueryData, h
 		}
 	}
 
+	hierarchy, err := getStackHierarchy(ctx, d)
+	if err != nil {
+		return nil, err
+	}
+
 	for _, path := range paths {
-		// Read files
-		content, err := os.ReadFile(path)
+		template, err := getTemplate(ctx, d, path)
 		if err != nil {
-			plugin.Logger(ctx).Error("awscfn_parameter.listAWSCloudFormationParameters", "file_error", err, "path", path)
-			return nil, fmt.Errorf("failed to read file %s: %w", path, err)
+			if skipInvalidTemplate(ctx, d, err) {
+				continue
+			}
+			return nil, err
 		}
-
-		// Parse file contents
-		var body interface{}
-		if err := yaml.Unmarshal(content, &body); err != nil {
-			panic(err)
+		for _, row := range template.parameterRows() {
+			row.stackPosition = stackPositionOf(hierarchy, path)
+			d.StreamListItem(ctx, row)
 		}
-		body = convert(body)
-
-		var result parametersStruct
-		if b, err := json.Marshal(body); err != nil {
-			panic(err)
-		} else {
-			err = json.Unmarshal(b, &result)
-			if err != nil {
-				plugin.Logger(ctx).Error("awscfn_parameter.listAWSCloudFormationParameters", "parse_error", err, "path", path)
-				return nil, fmt.Errorf("failed to unmarshal file content %s: %w", path, err)
+	}
+
+	return nil, nil
+}
+
+// parameterRows returns the rows of the awscfn_parameter table for the template
+func (t *cfnTemplate) parameterRows() []awsCFNParameter {
+	rows := t.lineNumbers("Parameters")
+
+	var parameters []awsCFNParameter
+	for k, v := range t.Parameters {
+		data := v.(map[string]interface{})
+
+		var lineNo int
+		for _, r := range rows {
+			if r.Name == k {
+				lineNo = r.StartLine
 			}
 		}
 
-		// Fail if no Resources attribute defined in template file
-		if result.Resources == nil {
-			plugin.Logger(ctx).Error("awscfn_parameter.listAWSCloudFormationParameters", "template_format_error", err, "path", path)
-			return nil, fmt.Errorf("failed to parse AWS CloudFormation template from file %s: Template format error: At least one Resources member must be defined", path)
+		// Broken defaults are otherwise only found when the stack is
+		// deployed
+		var defaultIsValid *bool
+		var validationErrors []string
+		if data["Default"] != nil {
+			var complete bool
+			validationErrors, complete = validateParameterValue(data, data["Default"])
+			// The default is only known to be valid if every constraint
+			// could be checked
+			if len(validationErrors) > 0 || complete {
+				defaultIsValid = types.Bool(len(validationErrors) == 0)
+			}
 		}
 
-		// Decode file contents
-		var root yaml.Node
-		r := bytes.NewReader(content)
-		decoder := yaml.NewDecoder(r)
-		err = decoder.Decode(&root)
+		parameters = append(parameters, awsCFNParameter{
+			Name:                  k,
+			Type:                  data["Type"].(string),
+			ParsedType:            parseParameterType(data["Type"].(string)),
+			DefaultValue:          formatParameterDefault(data["Default"]),
+			DefaultValueJSON:      data["Default"],
+			Description:           data["Description"],
+			AllowedPattern:        data["AllowedPattern"],
+			AllowedValues:         data["AllowedValues"],
+			ConstraintDescription: data["ConstraintDescription"],
+			MaxLength:             intAttribute(data["MaxLength"]),
+			MinLength:             intAttribute(data["MinLength"]),
+			MaxValue:              numberAttribute(data["MaxValue"]),
+			MinValue:              numberAttribute(data["MinValue"]),
+			NoEcho:                boolAttribute(data["NoEcho"]),
+			DefaultIsValid:        defaultIsValid,
+			ValidationErrors:      validationErrors,
+			StartLine:             lineNo,
+			Path:                  t.Path,
+		})
+	}
+	return parameters
+}
+
+// formatParameterDefault returns the default value as a string, in the same
+// way that CloudFormation treats it, e.g. 10 for a Number default of 10.0 and
+// a,b for a list default
+func formatParameterDefault(v interface{}) *string {
+	switch value := v.(type) {
+	case nil:
+		return nil
+	case []interface{}:
+		var items []string
+		for _, item := range value {
+			items = append(items, scalarString(item))
+		}
+		return types.String(strings.Join(items, ","))
+	case map[string]interface{}:
+		b, err := json.Marshal(value)
 		if err != nil {
-			plugin.Logger(ctx).Error("awscfn_parameter.listAWSCloudFormationParameters", "parse_error", err, "path", path)
-			return nil, fmt.Errorf("failed to parse file: %w", err)
+			return nil
 		}
-		var rows Rows
-		treeToList(&root, []string{}, &rows, "Parameters")
+		return types.String(string(b))
+	}
+	return types.String(scalarString(v))
+}
 
-		for k, v := range result.Parameters {
-			data := v.(map[string]interface{})
+// intAttribute returns the value of an integer attribute such as MaxLength,
+// which may be declared as a number or a string
+func intAttribute(v interface{}) *int64 {
+	n, ok := numberValue(v)
+	if !ok {
+		return nil
+	}
+	return types.Int64(int64(n))
+}
 
-			// Return error, if Parameters map has missing Type defined
-			if data["Type"] == nil {
-				plugin.Logger(ctx).Error("awscfn_parameter.listAWSCloudFormationParameters", "template_format_error", err, "path", path)
-				return nil, fmt.Errorf("failed to parse AWS CloudFormation template from file %s: Template format error: Every Parameters object must contain a Type member with non-null value", path)
-			}
+// numberAttribute returns the value of a numeric attribute such as MaxValue,
+// which may be declared as a number or a string
+func numberAttribute(v interface{}) *float64 {
+	n, ok := numberValue(v)
+	if !ok {
+		return nil
+	}
+	return types.Float64(n)
+}
 
-			var lineNo int
-			for _, r := range rows {
-				if r.Name == k {
-					lineNo = r.StartLine
-				}
-			}
-			d.StreamListItem(ctx, awsCFNParameter{
-				Name:                  k,
-				Type:                  data["Type"].(string),
-				DefaultValue:          data["Default"],
-				Description:           data["Description"],
-				AllowedPattern:        data["AllowedPattern"],
-				AllowedValues:         data["AllowedValues"],
-				ConstraintDescription: data["ConstraintDescription"],
-				MaxLength:             data["MaxLength"],
-				MinLength:             data["MinLength"],
-				MaxValue:              data["MaxValue"],
-				MinValue:              data["MinValue"],
-				NoEcho:                data["NoEcho"],
-				StartLine:             lineNo,
-				Path:                  path,
-			})
+// boolAttribute returns the value of a boolean attribute such as NoEcho, which
+// may be declared as a boolean or a string, e.g. "true"
+func boolAttribute(v interface{}) *bool {
+	switch value := v.(type) {
+	case bool:
+		return types.Bool(value)
+	case string:
+		b, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			return nil
 		}
+		return types.Bool(b)
 	}
-
-	return nil, nil
+	return nil
 }
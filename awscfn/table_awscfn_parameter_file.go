@@ -0,0 +1,209 @@
+package awscfn
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	filehelpers "github.com/turbot/go-kit/files"
+	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+)
+
+func tableAWSCFNParameterFile(ctx context.Context) *plugin.Table {
+	return &plugin.Table{
+		Name:        "awscfn_parameter_file",
+		Description: "CloudFormation stack parameter file values.",
+		List: &plugin.ListConfig{
+			Hydrate:    listAWSCloudFormationParameterFiles,
+			KeyColumns: plugin.OptionalColumns([]string{"path"}),
+		},
+		Columns: []*plugin.Column{
+			{
+				Name:        "key",
+				Description: "The name of the parameter.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "value",
+				Description: "The value of the parameter.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "format",
+				Description: "The format of the parameter file, one of cli for the format used by aws cloudformation create-stack, codepipeline for the CodePipeline template configuration format, or key_value for the format used by aws cloudformation deploy.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "template_path",
+				Description: "Path to the template the parameter file is for, if known. A template is linked if it is in the same directory as the parameter file or its parent directory, and either its name is a prefix of the parameter file name or it is the only template in that directory.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "start_line",
+				Description: "Starting line number.",
+				Type:        proto.ColumnType_INT,
+			},
+			{
+				Name:        "path",
+				Description: "Path to the parameter file.",
+				Type:        proto.ColumnType_STRING,
+			},
+		},
+	}
+}
+
+type awsCFNParameterFile struct {
+	Key          string
+	Value        string
+	Format       string
+	TemplatePath string
+	StartLine    int
+	Path         string
+}
+
+func listAWSCloudFormationParameterFiles(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateData) (interface{}, error) {
+	awscfnConfig := GetConfig(d.Connection)
+
+	// #1 - Path via qual
+	// If the path was requested through qualifier then match it exactly. Globs
+	// are not supported in this context since the output value for the column
+	// will never match the requested value.
+	//
+	// #2 - Path via glob paths in config
+	var paths []string
+	if d.EqualsQuals["path"] != nil {
+		paths = []string{d.EqualsQuals["path"].GetStringValue()}
+	} else {
+		var err error
+		paths, err = listParameterFilesByPath(d)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Templates are only linked if template paths are configured
+	var templates []string
+	if awscfnConfig.Paths != nil {
+		var err error
+		templates, err = listFilesByPath(ctx, d)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Parameter files are never templates, whichever of them were queried, so
+	// that the template linked to a parameter file does not depend on the
+	// path qual. Files that are not templates, e.g. package.json, are ignored.
+	parameterFiles, err := listParameterFilesByPath(d)
+	if err != nil {
+		return nil, err
+	}
+	isParameterFile := map[string]bool{}
+	for _, path := range append(parameterFiles, paths...) {
+		isParameterFile[path] = true
+	}
+	var candidates []string
+	for _, template := range templates {
+		if !isParameterFile[template] && loadTemplate(ctx, d, template).template.isTemplate() {
+			candidates = append(candidates, template)
+		}
+	}
+
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			plugin.Logger(ctx).Error("awscfn_parameter_file.listAWSCloudFormationParameterFiles", "file_error", err, "path", path)
+			return nil, fmt.Errorf("failed to read parameter file %s: %v", path, err)
+		}
+		values, format, err := parseParameterFile(content)
+		if err != nil {
+			plugin.Logger(ctx).Error("awscfn_parameter_file.listAWSCloudFormationParameterFiles", "parse_error", err, "path", path)
+			return nil, fmt.Errorf("failed to parse parameter file %s: %v", path, err)
+		}
+
+		templatePath := parameterFileTemplate(path, candidates)
+		for _, v := range values {
+			d.StreamListItem(ctx, awsCFNParameterFile{
+				Key:          v.Key,
+				Value:        v.Value,
+				Format:       format,
+				TemplatePath: templatePath,
+				StartLine:    v.Line,
+				Path:         path,
+			})
+		}
+	}
+
+	return nil, nil
+}
+
+// List all parameter files as per configured parameter file paths
+func listParameterFilesByPath(d *plugin.QueryData) ([]string, error) {
+	var fileList []string
+	for _, i := range GetConfig(d.Connection).ParameterFilePaths {
+		// List the files in the given source directory
+		files, err := d.GetSourceFiles(i)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			// Ignore directories
+			if filehelpers.DirectoryExists(file) {
+				continue
+			}
+			fileList = append(fileList, file)
+		}
+	}
+	return fileList, nil
+}
+
+// parameterFileTemplate returns the template the parameter file is for, or an
+// empty string if it cannot be determined. Only templates in the same
+// directory as the parameter file, or its parent directory, e.g. for
+// params/prod.json, are considered. A template whose name is a prefix of the
+// parameter file name, e.g. app.yaml for app-prod.json, is preferred to the
+// only template in a directory.
+func parameterFileTemplate(path string, templates []string) string {
+	dir := filepath.Dir(path)
+	searchDirs := []string{dir, filepath.Dir(dir)}
+	name := fileBaseName(path)
+
+	for _, searchDir := range searchDirs {
+		var match string
+		for _, template := range templates {
+			if filepath.Dir(template) != searchDir {
+				continue
+			}
+			templateName := fileBaseName(template)
+			if strings.HasPrefix(name, templateName) && (match == "" || len(templateName) > len(fileBaseName(match))) {
+				match = template
+			}
+		}
+		if match != "" {
+			return match
+		}
+	}
+
+	for _, searchDir := range searchDirs {
+		var inDir []string
+		for _, template := range templates {
+			if filepath.Dir(template) == searchDir {
+				inDir = append(inDir, template)
+			}
+		}
+		if len(inDir) == 1 {
+			return inDir[0]
+		}
+	}
+
+	return ""
+}
+
+// fileBaseName returns the name of the file without its directory or extension
+func fileBaseName(path string) string {
+	base := filepath.Base(path)
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
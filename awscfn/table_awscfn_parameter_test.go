@@ -0,0 +1,201 @@
+package awscfn
+
+import (
+	"testing"
+
+	"github.com/turbot/go-kit/types"
+)
+
+// parameterAttributeTemplates declare the same parameters in YAML and JSON
+var parameterAttributeTemplates = map[string]string{
+	"yaml": `
+Parameters:
+  Ratio:
+    Type: Number
+    MinValue: 0.5
+    MaxValue: 2.75
+    Default: 1.5
+  Count:
+    Type: Number
+    MinValue: "1"
+    MaxValue: "10"
+    Default: 3
+  Subnets:
+    Type: CommaDelimitedList
+    Default: [subnet-a, subnet-b]
+  Enabled:
+    Type: String
+    Default: true
+  Password:
+    Type: String
+    NoEcho: "true"
+    MinLength: "8"
+    MaxLength: 64
+  Token:
+    Type: String
+    NoEcho: true
+Resources:
+  Bucket:
+    Type: AWS::S3::Bucket
+`,
+	"json": `{
+  "Parameters": {
+    "Ratio": {
+      "Type": "Number",
+      "MinValue": 0.5,
+      "MaxValue": 2.75,
+      "Default": 1.5
+    },
+    "Count": {
+      "Type": "Number",
+      "MinValue": "1",
+      "MaxValue": "10",
+      "Default": 3
+    },
+    "Subnets": {
+      "Type": "CommaDelimitedList",
+      "Default": ["subnet-a", "subnet-b"]
+    },
+    "Enabled": {
+      "Type": "String",
+      "Default": true
+    },
+    "Password": {
+      "Type": "String",
+      "NoEcho": "true",
+      "MinLength": "8",
+      "MaxLength": 64
+    },
+    "Token": {
+      "Type": "String",
+      "NoEcho": true
+    }
+  },
+  "Resources": {
+    "Bucket": {
+      "Type": "AWS::S3::Bucket"
+    }
+  }
+}`,
+}
+
+func parameterAttributes(t *testing.T, format string) map[string]map[string]interface{} {
+	t.Helper()
+	path := "template." + format
+	template, errs := parseTemplateContent(path, []byte(parameterAttributeTemplates[format]))
+	if len(errs) > 0 {
+		t.Fatalf("parseTemplateContent() error = %v", errs[0])
+	}
+	parameters := map[string]map[string]interface{}{}
+	for name, v := range template.Parameters {
+		parameters[name] = v.(map[string]interface{})
+	}
+	return parameters
+}
+
+func TestFormatParameterDefault(t *testing.T) {
+	tests := []struct {
+		parameter string
+		expected  *string
+	}{
+		{"Ratio", types.String("1.5")},
+		{"Count", types.String("3")},
+		{"Subnets", types.String("subnet-a,subnet-b")},
+		{"Enabled", types.String("true")},
+		{"Password", nil},
+	}
+
+	for format := range parameterAttributeTemplates {
+		parameters := parameterAttributes(t, format)
+		for _, tt := range tests {
+			t.Run(format+"/"+tt.parameter, func(t *testing.T) {
+				got := formatParameterDefault(parameters[tt.parameter]["Default"])
+				if (got == nil) != (tt.expected == nil) || (got != nil && *got != *tt.expected) {
+					t.Errorf("formatParameterDefault() = %v, want %v", deref(got), deref(tt.expected))
+				}
+			})
+		}
+	}
+}
+
+func TestNumberAttribute(t *testing.T) {
+	tests := []struct {
+		parameter string
+		attribute string
+		expected  *float64
+	}{
+		{"Ratio", "MinValue", types.Float64(0.5)},
+		{"Ratio", "MaxValue", types.Float64(2.75)},
+		{"Count", "MinValue", types.Float64(1)},
+		{"Count", "MaxValue", types.Float64(10)},
+		{"Subnets", "MinValue", nil},
+	}
+
+	for format := range parameterAttributeTemplates {
+		parameters := parameterAttributes(t, format)
+		for _, tt := range tests {
+			t.Run(format+"/"+tt.parameter+"/"+tt.attribute, func(t *testing.T) {
+				got := numberAttribute(parameters[tt.parameter][tt.attribute])
+				if (got == nil) != (tt.expected == nil) || (got != nil && *got != *tt.expected) {
+					t.Errorf("numberAttribute() = %v, want %v", deref(got), deref(tt.expected))
+				}
+			})
+		}
+	}
+}
+
+func TestIntAttribute(t *testing.T) {
+	tests := []struct {
+		parameter string
+		attribute string
+		expected  *int64
+	}{
+		{"Password", "MinLength", types.Int64(8)},
+		{"Password", "MaxLength", types.Int64(64)},
+		{"Token", "MinLength", nil},
+	}
+
+	for format := range parameterAttributeTemplates {
+		parameters := parameterAttributes(t, format)
+		for _, tt := range tests {
+			t.Run(format+"/"+tt.parameter+"/"+tt.attribute, func(t *testing.T) {
+				got := intAttribute(parameters[tt.parameter][tt.attribute])
+				if (got == nil) != (tt.expected == nil) || (got != nil && *got != *tt.expected) {
+					t.Errorf("intAttribute() = %v, want %v", deref(got), deref(tt.expected))
+				}
+			})
+		}
+	}
+}
+
+func TestBoolAttribute(t *testing.T) {
+	tests := []struct {
+		parameter string
+		attribute string
+		expected  *bool
+	}{
+		{"Password", "NoEcho", types.Bool(true)},
+		{"Token", "NoEcho", types.Bool(true)},
+		{"Enabled", "Default", types.Bool(true)},
+		{"Ratio", "NoEcho", nil},
+	}
+
+	for format := range parameterAttributeTemplates {
+		parameters := parameterAttributes(t, format)
+		for _, tt := range tests {
+			t.Run(format+"/"+tt.parameter+"/"+tt.attribute, func(t *testing.T) {
+				got := boolAttribute(parameters[tt.parameter][tt.attribute])
+				if (got == nil) != (tt.expected == nil) || (got != nil && *got != *tt.expected) {
+					t.Errorf("boolAttribute() = %v, want %v", deref(got), deref(tt.expected))
+				}
+			})
+		}
+	}
+}
+
+func deref[T any](v *T) interface{} {
+	if v == nil {
+		return nil
+	}
+	return *v
+}
@@ -0,0 +1,73 @@
+package awscfn
+
+import (
+	"context"
+
+	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+)
+
+func tableAWSCFNParseError(ctx context.Context) *plugin.Table {
+	return &plugin.Table{
+		Name:        "awscfn_parse_error",
+		Description: "Files that could not be parsed as CloudFormation templates.",
+		List: &plugin.ListConfig{
+			Hydrate:    listAWSCloudFormationParseErrors,
+			KeyColumns: plugin.OptionalColumns([]string{"path"}),
+		},
+		Columns: []*plugin.Column{
+			{
+				Name:        "path",
+				Description: "Path to the file.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "stage",
+				Description: "The stage at which parsing failed, one of read, yaml, json, validation or goformation. Errors in the goformation stage do not prevent the template being queried.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "message",
+				Description: "The error message.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "line",
+				Description: "The line number of the error, if known.",
+				Type:        proto.ColumnType_INT,
+			},
+			{
+				Name:        "column",
+				Description: "The column number of the error, if known.",
+				Type:        proto.ColumnType_INT,
+			},
+		},
+	}
+}
+
+func listAWSCloudFormationParseErrors(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateData) (interface{}, error) {
+	// #1 - Path via qual
+	// If the path was requested through qualifier then match it exactly. Globs
+	// are not supported in this context since the output value for the column
+	// will never match the requested value.
+	//
+	// #2 - Path via glob paths in config
+	var paths []string
+	if d.EqualsQuals["path"] != nil {
+		paths = []string{d.EqualsQuals["path"].GetStringValue()}
+	} else {
+		var err error
+		paths, err = listFilesByPath(ctx, d)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, path := range paths {
+		for _, templateErr := range getTemplateErrors(ctx, d, path) {
+			d.StreamListItem(ctx, templateErr)
+		}
+	}
+
+	return nil, nil
+}
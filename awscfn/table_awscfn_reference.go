@@ -0,0 +1,116 @@
+package awscfn
+
+import (
+	"context"
+
+	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+)
+
+func tableAWSCFNReference(ctx context.Context) *plugin.Table {
+	return &plugin.Table{
+		Name:        "awscfn_reference",
+		Description: "References between the parameters, resources, mappings, conditions and outputs of CloudFormation templates.",
+		List: &plugin.ListConfig{
+			Hydrate:    listAWSCloudFormationReferences,
+			KeyColumns: plugin.OptionalColumns([]string{"path"}),
+		},
+		Columns: append([]*plugin.Column{
+			{
+				Name:        "source_section",
+				Description: "The template section that makes the reference, i.e. Rules, Conditions, Resources or Outputs.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "source_name",
+				Description: "The logical ID of the rule, condition, resource or output that makes the reference.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "property_path",
+				Description: "The path of the reference within the source, e.g. Properties.Tags[0].Value.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "function",
+				Description: "The intrinsic function or attribute that makes the reference, i.e. Ref, Fn::GetAtt, Fn::Sub, Fn::FindInMap, Fn::If, Condition or DependsOn.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "target_name",
+				Description: "The logical ID of the referenced parameter, resource, mapping or condition, or the name of a pseudo parameter, e.g. AWS::Region.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "target_attribute",
+				Description: "The referenced attribute of a resource for Fn::GetAtt and Fn::Sub, e.g. Arn, or the top level and second level keys of a Fn::FindInMap reference, e.g. us-east-1.AMI, if they are literals.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "target_section",
+				Description: "The template section the target is declared in, i.e. Parameters, Resources, Mappings or Conditions, or PseudoParameters for pseudo parameters. Resources generated by the AWS SAM transform are in Resources. Null if the target is not declared in the template.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "line",
+				Description: "The line number of the reference.",
+				Type:        proto.ColumnType_INT,
+			},
+			{
+				Name:        "path",
+				Description: "Path to the file.",
+				Type:        proto.ColumnType_STRING,
+			},
+		}, stackPositionColumns()...),
+	}
+}
+
+type awsCFNReference struct {
+	stackPosition
+	templateReference
+	Path string
+}
+
+func listAWSCloudFormationReferences(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateData) (interface{}, error) {
+	// #1 - Path via qual
+	// If the path was requested through qualifier then match it exactly. Globs
+	// are not supported in this context since the output value for the column
+	// will never match the requested value.
+	//
+	// #2 - Path via glob paths in config
+	var paths []string
+	if d.EqualsQuals["path"] != nil {
+		paths = []string{d.EqualsQuals["path"].GetStringValue()}
+	} else {
+		var err error
+		paths, err = listFilesByPath(ctx, d)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	hierarchy, err := getStackHierarchy(ctx, d)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, path := range paths {
+		template, err := getTemplate(ctx, d, path)
+		if err != nil {
+			if skipInvalidTemplate(ctx, d, err) {
+				continue
+			}
+			return nil, err
+		}
+
+		for _, reference := range template.references() {
+			d.StreamListItem(ctx, awsCFNReference{
+				templateReference: reference,
+				Path:              path,
+				stackPosition:     stackPositionOf(hierarchy, path),
+			})
+		}
+	}
+
+	return nil, nil
+}
@@ -1,17 +1,12 @@
 package awscfn
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
-	"fmt"
-	"os"
 
-	"github.com/awslabs/goformation/v6"
+	"github.com/turbot/go-kit/types"
 	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
 	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
 	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/transform"
-	"gopkg.in/yaml.v3"
 )
 
 func tableAWSCFNResource(ctx context.Context) *plugin.Table {
@@ -20,9 +15,9 @@ func tableAWSCFNResource(ctx context.Context) *plugin.Table {
 		Description: "CloudFormation resource information.",
 		List: &plugin.ListConfig{
 			Hydrate:    listAWSCloudFormationResources,
-			KeyColumns: plugin.OptionalColumns([]string{"path"}),
+			KeyColumns: plugin.OptionalColumns([]string{"path", "profile"}),
 		},
-		Columns: []*plugin.Column{
+		Columns: append([]*plugin.Column{
 			{
 				Name:        "name",
 				Description: "An identifier for the resource.",
@@ -41,7 +36,7 @@ func tableAWSCFNResource(ctx context.Context) *plugin.Table {
 			},
 			{
 				Name:        "properties",
-				Description: "Specifies the resource properties with calculated values as per given condition or parameter reference.",
+				Description: "Specifies the resource properties with intrinsic functions evaluated using the configured parameter values or defaults, mappings and conditions. Functions that cannot be evaluated, e.g. Fn::GetAtt, are left intact.",
 				Type:        proto.ColumnType_JSON,
 			},
 			{
@@ -49,6 +44,11 @@ func tableAWSCFNResource(ctx context.Context) *plugin.Table {
 				Description: "Specifies the resource conditions.",
 				Type:        proto.ColumnType_STRING,
 			},
+			{
+				Name:        "condition_resolved",
+				Description: "True if the resource condition evaluates to true using the configured parameter values or defaults, or if no condition is defined. Null if the condition cannot be evaluated.",
+				Type:        proto.ColumnType_BOOL,
+			},
 			{
 				Name:        "creation_policy",
 				Description: "Specifies the associated creation_policy with a resource to prevent its status from reaching create complete until AWS CloudFormation receives a specified number of success signals or the timeout period is exceeded.",
@@ -79,6 +79,16 @@ func tableAWSCFNResource(ctx context.Context) *plugin.Table {
 				Description: "Use the update_replace_policy attribute to retain or, in some cases, backup the existing physical instance of a resource when it's replaced during a stack update operation.",
 				Type:        proto.ColumnType_STRING,
 			},
+			{
+				Name:        "expanded_from",
+				Description: "The logical ID of the AWS::Serverless resource this resource was generated from, if expand_sam_resources is set in the connection config and the template uses the AWS::Serverless-2016-10-31 transform.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "profile",
+				Description: "The name of the profile in the connection config used to evaluate the properties and condition, or null if the connection values are used.",
+				Type:        proto.ColumnType_STRING,
+			},
 			{
 				Name:        "start_line",
 				Description: "Starting line number.",
@@ -89,31 +99,28 @@ func tableAWSCFNResource(ctx context.Context) *plugin.Table {
 				Description: "Path to the file.",
 				Type:        proto.ColumnType_STRING,
 			},
-		},
+		}, stackPositionColumns()...),
 	}
 }
 
 type awsCFNResource struct {
+	stackPosition
 	Name                string
 	StartLine           int
 	Type                string
 	Path                string
 	LiteralValue        interface{}
 	Properties          interface{}
+	Condition           interface{}
+	ConditionResolved   *bool
 	CreationPolicy      interface{}
 	DeletionPolicy      interface{}
 	DependsOn           interface{}
 	Metadata            interface{}
 	UpdatePolicy        interface{}
 	UpdateReplacePolicy interface{}
-}
-
-type resourceStruct struct {
-	Resources map[string]interface{} `cty:"Resources"`
-}
-
-type templateStruct struct {
-	Properties interface{} `json:"Properties"`
+	ExpandedFrom        string
+	Profile             string
 }
 
 func listAWSCloudFormationResources(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateData) (interface{}, error) {
@@ -134,118 +141,90 @@ func listAWSCloudFormationResources(ctx context.Context, d *plugin.QueryData, h
 		}
 	}
 
-	for _, path := range paths {
-		// Read files
-		content, err := os.ReadFile(path)
-		if err != nil {
-			plugin.Logger(ctx).Error("awscfn_resource.listAWSCloudFormationResources", "file_error", err, "path", path)
-			return nil, fmt.Errorf("failed to read file %s: %w", path, err)
-		}
+	hierarchy, err := getStackHierarchy(ctx, d)
+	if err != nil {
+		return nil, err
+	}
 
-		// Parse file contents
-		var body interface{}
-		content = formatFileContent(content)
-		if err := yaml.Unmarshal(content, &IncludeProcessor{&body}); err != nil {
-			panic(err)
-		}
-		body = convert(body)
-
-		var resourceData resourceStruct
-		if b, err := json.Marshal(body); err != nil {
-			panic(err)
-		} else {
-			err = json.Unmarshal(b, &resourceData)
-			if err != nil {
-				plugin.Logger(ctx).Error("awscfn_resource.listAWSCloudFormationResources", "parse_error", err, "path", path)
-				return nil, fmt.Errorf("failed to unmarshal file content %s: %w", path, err)
-			}
-		}
+	values, err := getEvaluationValues(ctx, d)
+	if err != nil {
+		return nil, err
+	}
 
-		// Fail if no Resources attribute defined in template file
-		if resourceData.Resources == nil {
-			plugin.Logger(ctx).Error("awscfn_resource.listAWSCloudFormationResources", "template_format_error", err, "path", path)
-			return nil, fmt.Errorf("failed to parse AWS CloudFormation template from file %s: Template format error: At least one Resources member must be defined", path)
-		}
+	awscfnConfig := GetConfig(d.Connection)
+	expandSAM := awscfnConfig.ExpandSAMResources != nil && *awscfnConfig.ExpandSAMResources
 
-		template, err := goformation.Open(path)
+	for _, path := range paths {
+		template, err := getTemplate(ctx, d, path)
 		if err != nil {
-			plugin.Logger(ctx).Error("awscfn_resource.listAWSCloudFormationResources", "goformation_file_error", err, "path", path)
+			if skipInvalidTemplate(ctx, d, err) {
+				continue
+			}
+			return nil, err
 		}
 
-		// Decode file contents
-		var root yaml.Node
-		r := bytes.NewReader(content)
-		decoder := yaml.NewDecoder(r)
-		err = decoder.Decode(&root)
-		if err != nil {
-			plugin.Logger(ctx).Error("awscfn_resource.listAWSCloudFormationResources", "parse_error", err, "path", path)
-			return nil, fmt.Errorf("failed to decode file content: %w", err)
+		// Each requested profile gets its own set of rows
+		for _, profileValues := range values {
+			for _, row := range template.resourceRows(profileValues, expandSAM) {
+				row.stackPosition = stackPositionOf(hierarchy, path)
+				d.StreamListItem(ctx, row)
+			}
 		}
-		var rows Rows
-		treeToList(&root, []string{}, &rows, "Resources")
+	}
 
-		for k, v := range resourceData.Resources {
-			data := v.(map[string]interface{})
+	return nil, nil
+}
 
-			// Return error, if Resources map has missing Type defined
-			if data["Type"] == nil {
-				plugin.Logger(ctx).Error("awscfn_resource.listAWSCloudFormationResources", "template_format_error", err, "path", path)
-				return nil, fmt.Errorf("failed to parse AWS CloudFormation template from file %s: Template format error: Every Resources object must contain a Type member. Resource: %s", path, k)
-			}
+// resourceRows returns the rows of the awscfn_resource table for the template,
+// with properties and conditions evaluated using the given values
+func (t *cfnTemplate) resourceRows(values *evaluationValues, expandSAM bool) []awsCFNResource {
+	rows := t.lineNumbers("Resources")
+	evaluator := newTemplateEvaluator(t, values)
 
-			// Return error if Properties defined with no value, or null
-			_, isPresent := data["Properties"]
-			if isPresent && data["Properties"] == nil {
-				plugin.Logger(ctx).Error("awscfn_resource.listAWSCloudFormationResources", "template_format_error", err, "path", path)
-				return nil, fmt.Errorf("[/Resources/%s/Properties] 'null' values are not allowed in templates. File: %s", k, path)
-			}
+	var resources []awsCFNResource
+	for _, resource := range t.resourceDefinitions(expandSAM) {
+		data := resource.Data
 
-			var lineNo int
-			for _, r := range rows {
-				if r.Name == k {
-					lineNo = r.StartLine
-				}
+		// Expanded resources are located at the SAM resource
+		lineName := resource.Name
+		if resource.ExpandedFrom != "" {
+			lineName = resource.ExpandedFrom
+		}
+		var lineNo int
+		for _, r := range rows {
+			if r.Name == lineName {
+				lineNo = r.StartLine
 			}
+		}
 
-			var propertyValue interface{}
-			if template != nil {
-				for mapKey, val := range template.Resources {
-					if k == mapKey {
-						reqBodyBytes := new(bytes.Buffer)
-						err := json.NewEncoder(reqBodyBytes).Encode(val)
-						if err != nil {
-							plugin.Logger(ctx).Error("awscfn_resource.listAWSCloudFormationResources", "parse_error", err, "path", path)
-							return nil, fmt.Errorf("failed to encode file content %s: %w", path, err)
-						}
-
-						byteData := reqBodyBytes.String()
-						var result templateStruct
-						err = json.Unmarshal([]byte(byteData), &result)
-						if err != nil {
-							plugin.Logger(ctx).Error("awscfn_resource.listAWSCloudFormationResources", "parse_error", err, "path", path)
-							return nil, fmt.Errorf("failed to unmarshal resource content: %w", err)
-						}
-						propertyValue = result.Properties
-					}
-				}
+		// Resources without a condition are always created
+		var conditionResolved *bool
+		if data["Condition"] == nil {
+			conditionResolved = types.Bool(true)
+		} else if name, ok := data["Condition"].(string); ok {
+			if result, ok := evaluator.evaluateCondition(name); ok {
+				conditionResolved = types.Bool(result)
 			}
-
-			d.StreamListItem(ctx, awsCFNResource{
-				Name:                k,
-				StartLine:           lineNo,
-				Type:                data["Type"].(string),
-				Path:                path,
-				LiteralValue:        data["Properties"],
-				Properties:          propertyValue,
-				CreationPolicy:      data["CreationPolicy"],
-				DeletionPolicy:      data["DeletionPolicy"],
-				DependsOn:           data["DependsOn"],
-				Metadata:            data["Metadata"],
-				UpdatePolicy:        data["UpdatePolicy"],
-				UpdateReplacePolicy: data["UpdateReplacePolicy"],
-			})
 		}
-	}
 
-	return nil, nil
+		resources = append(resources, awsCFNResource{
+			Name:                resource.Name,
+			StartLine:           lineNo,
+			Type:                data["Type"].(string),
+			Path:                t.Path,
+			LiteralValue:        data["Properties"],
+			Properties:          evaluator.evaluate(data["Properties"]),
+			Condition:           data["Condition"],
+			ConditionResolved:   conditionResolved,
+			CreationPolicy:      data["CreationPolicy"],
+			DeletionPolicy:      data["DeletionPolicy"],
+			DependsOn:           data["DependsOn"],
+			Metadata:            data["Metadata"],
+			UpdatePolicy:        data["UpdatePolicy"],
+			UpdateReplacePolicy: data["UpdateReplacePolicy"],
+			ExpandedFrom:        resource.ExpandedFrom,
+			Profile:             values.Profile,
+		})
+	}
+	return resources
 }
@@ -0,0 +1,236 @@
+package awscfn
+
+import (
+	"context"
+	"strings"
+
+	"github.com/turbot/go-kit/types"
+	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/transform"
+)
+
+// samEventSourceProperties maps event types to the property that identifies
+// the source of their events
+var samEventSourceProperties = map[string]string{
+	"AlexaSkill":       "SkillId",
+	"CloudWatchEvent":  "Pattern",
+	"CloudWatchLogs":   "LogGroupName",
+	"Cognito":          "UserPool",
+	"DocumentDB":       "Cluster",
+	"DynamoDB":         "Stream",
+	"EventBridgeRule":  "Pattern",
+	"IoTRule":          "Sql",
+	"Kinesis":          "Stream",
+	"MQ":               "Broker",
+	"MSK":              "Stream",
+	"S3":               "Bucket",
+	"SelfManagedKafka": "KafkaBootstrapServers",
+	"SNS":              "Topic",
+	"SQS":              "Queue",
+}
+
+func tableAWSCFNSAMEvent(ctx context.Context) *plugin.Table {
+	return &plugin.Table{
+		Name:        "awscfn_sam_event",
+		Description: "Event sources of the serverless functions declared in AWS SAM templates.",
+		List: &plugin.ListConfig{
+			Hydrate:    listAWSCloudFormationSAMEvents,
+			KeyColumns: plugin.OptionalColumns([]string{"path"}),
+		},
+		Columns: append([]*plugin.Column{
+			{
+				Name:        "function_name",
+				Description: "The logical ID of the AWS::Serverless::Function.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "event_name",
+				Description: "The name of the event in the Events property of the function.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "event_type",
+				Description: "The type of the event source, e.g. Api, HttpApi, S3, SQS, Schedule or EventBridgeRule.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "properties_src",
+				Description: "The properties of the event, as declared in the template.",
+				Type:        proto.ColumnType_JSON,
+			},
+			{
+				Name:        "properties",
+				Description: "The properties of the event, with intrinsic functions evaluated in the same way as the properties column of the awscfn_resource table.",
+				Type:        proto.ColumnType_JSON,
+			},
+			{
+				Name:        "api_id",
+				Description: "The logical ID of the API of an Api or HttpApi event, i.e. the RestApiId or ApiId property, or ServerlessRestApi or ServerlessHttpApi for the API SAM creates if it is not set.",
+				Type:        proto.ColumnType_STRING,
+				Transform:   transform.FromField("APIID").NullIfZero(),
+			},
+			{
+				Name:        "method",
+				Description: "The HTTP method of an Api or HttpApi event in upper case, e.g. GET, or ANY for all methods.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "api_path",
+				Description: "The path of an Api or HttpApi event, e.g. /items/{id}, or $default for the default route of an HTTP API.",
+				Type:        proto.ColumnType_STRING,
+				Transform:   transform.FromField("APIPath").NullIfZero(),
+			},
+			{
+				Name:        "schedule",
+				Description: "The schedule expression of a Schedule or ScheduleV2 event, e.g. rate(1 day).",
+				Type:        proto.ColumnType_JSON,
+			},
+			{
+				Name:        "source",
+				Description: "The source of the events, e.g. the Queue of an SQS event, the Stream of a Kinesis or DynamoDB event, the Topic of an SNS event, the Bucket of an S3 event, or the Pattern of an EventBridgeRule event.",
+				Type:        proto.ColumnType_JSON,
+			},
+			{
+				Name:        "enabled",
+				Description: "False if the event source is disabled using the Enabled or State property, true if it is enabled, or null if it is not set.",
+				Type:        proto.ColumnType_BOOL,
+			},
+			{
+				Name:        "start_line",
+				Description: "Starting line number.",
+				Type:        proto.ColumnType_INT,
+			},
+			{
+				Name:        "path",
+				Description: "Path to the file.",
+				Type:        proto.ColumnType_STRING,
+			},
+		}, stackPositionColumns()...),
+	}
+}
+
+type awsCFNSAMEvent struct {
+	stackPosition
+	FunctionName  string
+	EventName     string
+	EventType     string
+	PropertiesSrc interface{}
+	Properties    interface{}
+	APIID         string
+	Method        string
+	APIPath       string
+	Schedule      interface{}
+	Source        interface{}
+	Enabled       *bool
+	StartLine     int
+	Path          string
+}
+
+func listAWSCloudFormationSAMEvents(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateData) (interface{}, error) {
+	// #1 - Path via qual
+	// If the path was requested through qualifier then match it exactly. Globs
+	// are not supported in this context since the output value for the column
+	// will never match the requested value.
+	//
+	// #2 - Path via glob paths in config
+	var paths []string
+	if d.EqualsQuals["path"] != nil {
+		paths = []string{d.EqualsQuals["path"].GetStringValue()}
+	} else {
+		var err error
+		paths, err = listFilesByPath(ctx, d)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	hierarchy, err := getStackHierarchy(ctx, d)
+	if err != nil {
+		return nil, err
+	}
+
+	// Events are evaluated using the connection values, since the table has
+	// no profile column
+	values, err := getEvaluationValues(ctx, d)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, path := range paths {
+		template, err := getTemplate(ctx, d, path)
+		if err != nil {
+			if skipInvalidTemplate(ctx, d, err) {
+				continue
+			}
+			return nil, err
+		}
+		evaluator := newTemplateEvaluator(template, values[0])
+
+		for _, event := range template.samEvents() {
+			row := awsCFNSAMEvent{
+				FunctionName:  event.FunctionName,
+				EventName:     event.EventName,
+				EventType:     event.EventType,
+				PropertiesSrc: event.Properties,
+				Properties:    evaluator.evaluate(event.Properties),
+				StartLine:     event.Line,
+				Path:          path,
+				stackPosition: stackPositionOf(hierarchy, path),
+			}
+			row.normalize()
+			d.StreamListItem(ctx, row)
+		}
+	}
+
+	return nil, nil
+}
+
+// normalize sets the columns that are common to several event types from
+// the evaluated event properties
+func (row *awsCFNSAMEvent) normalize() {
+	properties, _ := row.Properties.(map[string]interface{})
+
+	switch row.EventType {
+	case "Api", "HttpApi":
+		var apiProperty string
+		apiProperty, row.APIID = "RestApiId", samImplicitRestAPI
+		if row.EventType == "HttpApi" {
+			apiProperty, row.APIID = "ApiId", samImplicitHTTPAPI
+		}
+		switch api := properties[apiProperty].(type) {
+		case string:
+			row.APIID = api
+		case map[string]interface{}:
+			name, _ := api["Ref"].(string)
+			row.APIID = name
+		}
+
+		row.Method, _ = properties["Method"].(string)
+		row.APIPath, _ = properties["Path"].(string)
+		// An HTTP API event without a path is the default route
+		if row.EventType == "HttpApi" && row.APIPath == "" {
+			row.APIPath = "$default"
+		}
+		row.Method = strings.ToUpper(row.Method)
+		if row.Method == "" {
+			row.Method = "ANY"
+		}
+
+	case "Schedule":
+		row.Schedule = properties["Schedule"]
+
+	case "ScheduleV2":
+		row.Schedule = properties["ScheduleExpression"]
+
+	default:
+		row.Source = properties[samEventSourceProperties[row.EventType]]
+	}
+
+	// Enabled may be a string, e.g. the value of a parameter
+	if enabled := boolAttribute(properties["Enabled"]); enabled != nil {
+		row.Enabled = enabled
+	} else if state, ok := properties["State"].(string); ok {
+		row.Enabled = types.Bool(strings.EqualFold(strings.TrimSpace(state), "ENABLED"))
+	}
+}
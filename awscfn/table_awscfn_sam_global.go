@@ -0,0 +1,102 @@
+package awscfn
+
+import (
+	"context"
+
+	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+)
+
+func tableAWSCFNSAMGlobal(ctx context.Context) *plugin.Table {
+	return &plugin.Table{
+		Name:        "awscfn_sam_global",
+		Description: "Properties declared in the Globals section of AWS SAM templates.",
+		List: &plugin.ListConfig{
+			Hydrate:    listAWSCloudFormationSAMGlobals,
+			KeyColumns: plugin.OptionalColumns([]string{"path"}),
+		},
+		Columns: append([]*plugin.Column{
+			{
+				Name:        "resource_type",
+				Description: "The type of the SAM resources the property applies to, e.g. AWS::Serverless::Function for the Function section.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "property",
+				Description: "The name of the property, e.g. Runtime.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "value",
+				Description: "The value of the property.",
+				Type:        proto.ColumnType_JSON,
+			},
+			{
+				Name:        "start_line",
+				Description: "Starting line number.",
+				Type:        proto.ColumnType_INT,
+			},
+			{
+				Name:        "path",
+				Description: "Path to the file.",
+				Type:        proto.ColumnType_STRING,
+			},
+		}, stackPositionColumns()...),
+	}
+}
+
+type awsCFNSAMGlobal struct {
+	stackPosition
+	ResourceType string
+	Property     string
+	Value        interface{}
+	StartLine    int
+	Path         string
+}
+
+func listAWSCloudFormationSAMGlobals(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateData) (interface{}, error) {
+	// #1 - Path via qual
+	// If the path was requested through qualifier then match it exactly. Globs
+	// are not supported in this context since the output value for the column
+	// will never match the requested value.
+	//
+	// #2 - Path via glob paths in config
+	var paths []string
+	if d.EqualsQuals["path"] != nil {
+		paths = []string{d.EqualsQuals["path"].GetStringValue()}
+	} else {
+		var err error
+		paths, err = listFilesByPath(ctx, d)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	hierarchy, err := getStackHierarchy(ctx, d)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, path := range paths {
+		template, err := getTemplate(ctx, d, path)
+		if err != nil {
+			if skipInvalidTemplate(ctx, d, err) {
+				continue
+			}
+			return nil, err
+		}
+
+		for _, global := range template.samGlobals() {
+			d.StreamListItem(ctx, awsCFNSAMGlobal{
+				ResourceType:  global.ResourceType,
+				Property:      global.Property,
+				Value:         global.Value,
+				StartLine:     global.Line,
+				Path:          path,
+				stackPosition: stackPositionOf(hierarchy, path),
+			})
+		}
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,208 @@
+package awscfn
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/transform"
+)
+
+func tableAWSCFNTemplate(ctx context.Context) *plugin.Table {
+	return &plugin.Table{
+		Name:        "awscfn_template",
+		Description: "CloudFormation template information.",
+		List: &plugin.ListConfig{
+			Hydrate:    listAWSCloudFormationTemplates,
+			KeyColumns: plugin.OptionalColumns([]string{"path"}),
+		},
+		Columns: append([]*plugin.Column{
+			{
+				Name:        "path",
+				Description: "Path to the file.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "format_version",
+				Description: "The AWS CloudFormation template version that the template conforms to.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "description",
+				Description: "A text string that describes the template.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "transform",
+				Description: "A list of macros that AWS CloudFormation uses to process the template, e.g. AWS::Serverless-2016-10-31.",
+				Type:        proto.ColumnType_JSON,
+			},
+			{
+				Name:        "metadata",
+				Description: "Objects that provide additional information about the template.",
+				Type:        proto.ColumnType_JSON,
+			},
+			{
+				Name:        "format",
+				Description: "The format of the template file, either json or yaml.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "size",
+				Description: "The size of the template file, in bytes.",
+				Type:        proto.ColumnType_INT,
+			},
+			{
+				Name:        "sha256",
+				Description: "The hex encoded SHA-256 hash of the template file content.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "parameter_count",
+				Description: "The number of parameters declared in the template.",
+				Type:        proto.ColumnType_INT,
+				Transform:   transform.FromField("ParameterCount"),
+			},
+			{
+				Name:        "mapping_count",
+				Description: "The number of mappings declared in the template.",
+				Type:        proto.ColumnType_INT,
+				Transform:   transform.FromField("MappingCount"),
+			},
+			{
+				Name:        "condition_count",
+				Description: "The number of conditions declared in the template.",
+				Type:        proto.ColumnType_INT,
+				Transform:   transform.FromField("ConditionCount"),
+			},
+			{
+				Name:        "resource_count",
+				Description: "The number of resources declared in the template.",
+				Type:        proto.ColumnType_INT,
+				Transform:   transform.FromField("ResourceCount"),
+			},
+			{
+				Name:        "output_count",
+				Description: "The number of outputs declared in the template.",
+				Type:        proto.ColumnType_INT,
+				Transform:   transform.FromField("OutputCount"),
+			},
+			{
+				Name:        "graph_dot",
+				Description: "The resource dependency graph of the template in the Graphviz DOT language. Edges point from a resource to the resource it depends on, and are dashed if only declared by DependsOn, or red if part of a circular dependency.",
+				Type:        proto.ColumnType_STRING,
+				Transform:   transform.From(templateGraphDOT),
+			},
+			{
+				Name:        "graph_mermaid",
+				Description: "The resource dependency graph of the template as a Mermaid flowchart, drawn in the same way as graph_dot.",
+				Type:        proto.ColumnType_STRING,
+				Transform:   transform.From(templateGraphMermaid),
+			},
+		}, stackPositionColumns()...),
+	}
+}
+
+type awsCFNTemplate struct {
+	stackPosition
+	Path           string
+	FormatVersion  interface{}
+	Description    interface{}
+	Transform      interface{}
+	Metadata       interface{}
+	Format         string
+	Size           int
+	Sha256         string
+	ParameterCount int
+	MappingCount   int
+	ConditionCount int
+	ResourceCount  int
+	OutputCount    int
+
+	template *cfnTemplate
+}
+
+func listAWSCloudFormationTemplates(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateData) (interface{}, error) {
+	// #1 - Path via qual
+	// If the path was requested through qualifier then match it exactly. Globs
+	// are not supported in this context since the output value for the column
+	// will never match the requested value.
+	//
+	// #2 - Path via glob paths in config
+	var paths []string
+	if d.EqualsQuals["path"] != nil {
+		paths = []string{d.EqualsQuals["path"].GetStringValue()}
+	} else {
+		var err error
+		paths, err = listFilesByPath(ctx, d)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	hierarchy, err := getStackHierarchy(ctx, d)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, path := range paths {
+		template, err := getTemplate(ctx, d, path)
+		if err != nil {
+			if skipInvalidTemplate(ctx, d, err) {
+				continue
+			}
+			return nil, err
+		}
+		content := template.Content
+
+		// YAML is a superset of JSON, so the format can only be determined by
+		// checking whether the content is valid JSON
+		format := "yaml"
+		if json.Valid(content) {
+			format = "json"
+		}
+
+		// Transform may be declared as a single macro name or a list of
+		// macros, so always return it as a list
+		transforms := template.Transform
+		if transforms != nil {
+			if _, isArray := transforms.([]interface{}); !isArray {
+				transforms = []interface{}{transforms}
+			}
+		}
+
+		hash := sha256.Sum256(content)
+
+		d.StreamListItem(ctx, awsCFNTemplate{
+			Path:           path,
+			stackPosition:  stackPositionOf(hierarchy, path),
+			FormatVersion:  template.AWSTemplateFormatVersion,
+			Description:    template.Description,
+			Transform:      transforms,
+			Metadata:       template.Metadata,
+			Format:         format,
+			Size:           len(content),
+			Sha256:         hex.EncodeToString(hash[:]),
+			ParameterCount: len(template.Parameters),
+			MappingCount:   len(template.Mappings),
+			ConditionCount: len(template.Conditions),
+			ResourceCount:  len(template.Resources),
+			OutputCount:    len(template.Outputs),
+			template:       template,
+		})
+	}
+
+	return nil, nil
+}
+
+// The graphs are only rendered if the columns are requested
+func templateGraphDOT(ctx context.Context, d *transform.TransformData) (interface{}, error) {
+	return d.HydrateItem.(awsCFNTemplate).template.graphDOT(), nil
+}
+
+func templateGraphMermaid(ctx context.Context, d *transform.TransformData) (interface{}, error) {
+	return d.HydrateItem.(awsCFNTemplate).template.graphMermaid(), nil
+}
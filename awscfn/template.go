@@ -0,0 +1,503 @@
+package awscfn
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/awslabs/goformation/v6"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+	"gopkg.in/yaml.v3"
+)
+
+// cfnTemplate is the parsed content of a CloudFormation template file. It is
+// shared by all tables and cached in the connection cache, so it must be
+// treated as read only once parsed.
+type cfnTemplate struct {
+	TemplateStruct
+
+	Path    string
+	Content []byte
+
+	// Root is the YAML node tree of the file, with short form intrinsic
+	// function tags replaced by their full form. It is used to look up line
+	// numbers.
+	Root yaml.Node
+
+	// GoformationError describes why goformation rejected the template, e.g.
+	// a property value that does not match its resource schema. It does not
+	// prevent the template being queried.
+	GoformationError *templateError
+}
+
+type TemplateStruct struct {
+	AWSTemplateFormatVersion interface{}            `cty:"AWSTemplateFormatVersion"`
+	Description              interface{}            `cty:"Description"`
+	Transform                interface{}            `cty:"Transform"`
+	Metadata                 interface{}            `cty:"Metadata"`
+	Parameters               map[string]interface{} `cty:"Parameters"`
+	Mappings                 map[string]interface{} `cty:"Mappings"`
+	Conditions               map[string]interface{} `cty:"Conditions"`
+	Resources                map[string]interface{} `cty:"Resources"`
+	Outputs                  map[string]interface{} `cty:"Outputs"`
+	// Globals is only used by templates with the AWS::Serverless transform
+	Globals map[string]interface{} `cty:"Globals"`
+}
+
+// Stages at which parsing a template file can fail
+const (
+	templateErrorStageRead        = "read"
+	templateErrorStageYAML        = "yaml"
+	templateErrorStageJSON        = "json"
+	templateErrorStageValidation  = "validation"
+	templateErrorStageGoformation = "goformation"
+)
+
+// templateError describes why a template file could not be parsed, including
+// the position of the problem in the file when it is known
+type templateError struct {
+	Path    string
+	Stage   string
+	Message string
+	Line    int
+	Column  int
+}
+
+func (e *templateError) Error() string {
+	location := e.Path
+	if e.Line > 0 {
+		location = fmt.Sprintf("%s (line %d", location, e.Line)
+		if e.Column > 0 {
+			location = fmt.Sprintf("%s, column %d", location, e.Column)
+		}
+		location += ")"
+	}
+	return fmt.Sprintf("failed to parse AWS CloudFormation template from file %s: %s", location, e.Message)
+}
+
+var yamlErrorLineRegex = regexp.MustCompile(`line (\d+): `)
+
+// newYAMLError converts an error returned by the YAML parser into a
+// templateError. The YAML parser only reports the line of the problem, so if
+// the file is JSON, the JSON parser is used to locate the exact position.
+func newYAMLError(path string, content []byte, err error) *templateError {
+	if jsonErr := newJSONSyntaxError(path, content); jsonErr != nil {
+		return jsonErr
+	}
+
+	message := strings.TrimPrefix(err.Error(), "yaml: ")
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) && len(typeErr.Errors) > 0 {
+		message = typeErr.Errors[0]
+	}
+
+	e := &templateError{
+		Path:    path,
+		Stage:   templateErrorStageYAML,
+		Message: message,
+	}
+	if match := yamlErrorLineRegex.FindStringSubmatchIndex(message); match != nil {
+		e.Line, _ = strconv.Atoi(message[match[2]:match[3]])
+		e.Message = message[:match[0]] + message[match[1]:]
+	}
+	return e
+}
+
+// newJSONSyntaxError returns the position of the syntax error in a JSON file,
+// or nil if the file is not JSON or has no syntax error
+func newJSONSyntaxError(path string, content []byte) *templateError {
+	trimmed := bytes.TrimSpace(content)
+	if len(trimmed) == 0 || trimmed[0] != '{' {
+		return nil
+	}
+
+	var body interface{}
+	var syntaxErr *json.SyntaxError
+	if err := json.Unmarshal(content, &body); !errors.As(err, &syntaxErr) {
+		return nil
+	}
+
+	line, column := offsetToPosition(content, syntaxErr.Offset)
+	return &templateError{
+		Path:    path,
+		Stage:   templateErrorStageJSON,
+		Message: syntaxErr.Error(),
+		Line:    line,
+		Column:  column,
+	}
+}
+
+// offsetToPosition converts a byte offset in the content to a line and column
+func offsetToPosition(content []byte, offset int64) (int, int) {
+	if offset > int64(len(content)) {
+		offset = int64(len(content))
+	}
+	before := content[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	column := len(before) - bytes.LastIndexByte(before, '\n')
+	return line, column
+}
+
+// skipInvalidTemplate returns true if the template that failed to parse should
+// be skipped rather than failing the query, i.e. if skip_invalid_templates is
+// enabled in the connection config. Skipped templates are reported in the
+// plugin logs.
+func skipInvalidTemplate(ctx context.Context, d *plugin.QueryData, err error) bool {
+	awscfnConfig := GetConfig(d.Connection)
+	if awscfnConfig.SkipInvalidTemplates == nil || !*awscfnConfig.SkipInvalidTemplates {
+		return false
+	}
+	plugin.Logger(ctx).Warn("skipInvalidTemplate", "error", err)
+	return true
+}
+
+// cachedTemplate is the result of parsing a template file. Failures are cached
+// too, so that an invalid file is only parsed once. The template is never nil,
+// but is only fully populated if there are no errors.
+type cachedTemplate struct {
+	template *cfnTemplate
+	errors   []*templateError
+}
+
+// getTemplate returns the parsed template for the given file. Templates are
+// parsed once per file version, i.e. path, modification time and size, and
+// cached in the connection cache so that every table (and every table in a
+// join) shares the same parsed content. The returned error is a
+// *templateError.
+func getTemplate(ctx context.Context, d *plugin.QueryData, path string) (*cfnTemplate, error) {
+	result := loadTemplate(ctx, d, path)
+	if len(result.errors) > 0 {
+		return nil, result.errors[0]
+	}
+	return result.template, nil
+}
+
+// getTemplateErrors returns all the errors found while parsing the given file,
+// including goformation errors which do not prevent the template being used
+func getTemplateErrors(ctx context.Context, d *plugin.QueryData, path string) []*templateError {
+	result := loadTemplate(ctx, d, path)
+	if len(result.errors) == 0 && result.template.GoformationError != nil {
+		return []*templateError{result.template.GoformationError}
+	}
+	return result.errors
+}
+
+func loadTemplate(ctx context.Context, d *plugin.QueryData, path string) *cachedTemplate {
+	info, err := os.Stat(path)
+	if err != nil {
+		plugin.Logger(ctx).Error("loadTemplate", "file_error", err, "path", path)
+		return &cachedTemplate{
+			template: &cfnTemplate{Path: path},
+			errors:   []*templateError{{Path: path, Stage: templateErrorStageRead, Message: err.Error()}},
+		}
+	}
+
+	cacheKey := fmt.Sprintf("awscfn_template-%s-%d-%d", path, info.ModTime().UnixNano(), info.Size())
+	if cached, ok := d.ConnectionCache.Get(ctx, cacheKey); ok {
+		return cached.(*cachedTemplate)
+	}
+
+	template, errs := parseTemplate(ctx, path)
+	result := &cachedTemplate{template: template, errors: errs}
+	if err := d.ConnectionCache.Set(ctx, cacheKey, result); err != nil {
+		plugin.Logger(ctx).Error("loadTemplate", "cache_error", err, "path", path)
+	}
+
+	return result
+}
+
+// parseTemplate reads and parses a CloudFormation template file. If the file
+// is not a valid template, the errors describe why and the returned template
+// is only populated up to the stage that failed.
+func parseTemplate(ctx context.Context, path string) (*cfnTemplate, []*templateError) {
+	// Read files
+	content, err := os.ReadFile(path)
+	if err != nil {
+		plugin.Logger(ctx).Error("parseTemplate", "parse_error", err, "path", path, "stage", templateErrorStageRead)
+		return &cfnTemplate{Path: path}, []*templateError{{Path: path, Stage: templateErrorStageRead, Message: err.Error()}}
+	}
+
+	template, errs := parseTemplateContent(path, content)
+	if len(errs) > 0 {
+		if errs[0].Stage == templateErrorStageValidation {
+			plugin.Logger(ctx).Error("parseTemplate", "template_format_error", errs[0].Message, "path", path)
+		} else {
+			plugin.Logger(ctx).Error("parseTemplate", "parse_error", errs[0].Message, "path", path, "stage", errs[0].Stage)
+		}
+	} else if template.GoformationError != nil {
+		plugin.Logger(ctx).Error("parseTemplate", "goformation_file_error", template.GoformationError.Message, "path", path)
+	}
+	return template, errs
+}
+
+// parseTemplateContent parses the content of a CloudFormation template file.
+// Every table uses the result through getTemplate, so a template is either
+// accepted or rejected by all of them.
+func parseTemplateContent(path string, content []byte) (*cfnTemplate, []*templateError) {
+	template := &cfnTemplate{
+		Path:    path,
+		Content: content,
+	}
+
+	fail := func(e *templateError) (*cfnTemplate, []*templateError) {
+		return template, []*templateError{e}
+	}
+
+	// Decode file contents. An empty file is not a syntax error, and is
+	// reported below as a template with no Resources.
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	err := decoder.Decode(&template.Root)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return fail(newYAMLError(path, content, err))
+	}
+
+	// Replace short form intrinsic function tags, e.g. !Sub, on the node tree
+	// rather than the raw content, so that literal text is never modified and
+	// the line numbers of the nodes still match the file
+	if _, err := resolveCustomTags(&template.Root); err != nil {
+		return fail(newYAMLError(path, content, err))
+	}
+
+	// Parse file contents
+	var body interface{}
+	if template.Root.Kind != 0 {
+		if err := template.Root.Decode(&body); err != nil {
+			return fail(newYAMLError(path, content, err))
+		}
+	}
+	body = convert(body)
+
+	b, err := json.Marshal(body)
+	if err != nil {
+		return fail(&templateError{Path: path, Stage: templateErrorStageJSON, Message: err.Error()})
+	}
+	err = json.Unmarshal(b, &template.TemplateStruct)
+	if err != nil {
+		e := &templateError{Path: path, Stage: templateErrorStageJSON, Message: err.Error()}
+
+		// The template sections must be objects, e.g. Resources can not be
+		// a list, so report the position of the invalid section
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			e.Stage = templateErrorStageValidation
+			e.Message = fmt.Sprintf("Template format error: %s must be an object, found %s", typeErr.Field, typeErr.Value)
+			e.Line, e.Column = template.keyPosition(typeErr.Field)
+		}
+		return fail(e)
+	}
+
+	if errs := template.validate(); len(errs) > 0 {
+		return template, errs
+	}
+
+	// Check the template against the goformation resource schemas. Failures
+	// are not fatal, since resource properties are evaluated natively
+	if err := validateGoformationSchema(path, content); err != nil {
+		template.GoformationError = &templateError{Path: path, Stage: templateErrorStageGoformation, Message: err.Error()}
+	}
+
+	return template, nil
+}
+
+// validateGoformationSchema returns an error if goformation cannot parse the
+// template into its resource schemas
+func validateGoformationSchema(path string, content []byte) error {
+	var err error
+	if strings.HasSuffix(path, ".json") {
+		_, err = goformation.ParseJSON(content)
+	} else {
+		_, err = goformation.ParseYAML(content)
+	}
+	return err
+}
+
+var (
+	// Resource types are namespaced, e.g. AWS::S3::Bucket, Custom::MyResource
+	// or a third party type such as MyOrg::MyService::MyResource
+	templateResourceTypeRegex = regexp.MustCompile(`^(AWS|Alexa|Custom)::[A-Za-z0-9]|^[A-Za-z0-9]+::[A-Za-z0-9]+::[A-Za-z0-9]+`)
+	// Used when the file can not be parsed as YAML
+	templateContentRegex = regexp.MustCompile(`AWSTemplateFormatVersion|["']?Type["']?\s*:\s*["']?(AWS|Alexa|Custom)::`)
+)
+
+// isTemplate returns true if the file looks like a CloudFormation template,
+// i.e. it declares AWSTemplateFormatVersion at the top level, or a Resources
+// mapping containing at least one resource with a namespaced resource type.
+// Files that are not valid YAML are sniffed using their raw content, so that
+// broken templates are still reported.
+func (t *cfnTemplate) isTemplate() bool {
+	if t.Root.Kind == 0 {
+		return templateContentRegex.Match(t.Content)
+	}
+
+	node := &t.Root
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+	if node.Kind != yaml.MappingNode {
+		return false
+	}
+
+	for i := 0; i < len(node.Content)-1; i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		switch key.Value {
+		case "AWSTemplateFormatVersion":
+			return true
+		case "Resources":
+			if value.Kind != yaml.MappingNode {
+				continue
+			}
+			for j := 1; j < len(value.Content); j += 2 {
+				resource := value.Content[j]
+				if resource.Kind != yaml.MappingNode {
+					continue
+				}
+				for k := 0; k < len(resource.Content)-1; k += 2 {
+					if resource.Content[k].Value == "Type" && templateResourceTypeRegex.MatchString(resource.Content[k+1].Value) {
+						return true
+					}
+				}
+			}
+		}
+	}
+
+	return false
+}
+
+// lineNumbers returns the starting line numbers of the members of the given
+// template section, e.g. Resources, keyed by the name reported by treeToList
+func (t *cfnTemplate) lineNumbers(section string) Rows {
+	var rows Rows
+	treeToList(&t.Root, []string{}, &rows, section)
+	return rows
+}
+
+// keyPosition returns the line and column of the given key path in the
+// template, e.g. ["Resources", "MyBucket"], or zero if it can not be found
+func (t *cfnTemplate) keyPosition(keys ...string) (int, int) {
+	node := &t.Root
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+
+	line, column := 0, 0
+	for _, key := range keys {
+		if node.Kind != yaml.MappingNode {
+			return 0, 0
+		}
+		var found bool
+		for i := 0; i < len(node.Content)-1; i += 2 {
+			if node.Content[i].Value == key {
+				line, column = node.Content[i].Line, node.Content[i].Column
+				node = node.Content[i+1]
+				found = true
+				break
+			}
+		}
+		if !found {
+			return 0, 0
+		}
+	}
+	return line, column
+}
+
+// validate checks the template against the structural rules that CloudFormation
+// enforces, so that every table accepts or rejects the same templates. All
+// problems are returned, ordered by section and name.
+func (t *cfnTemplate) validate() []*templateError {
+	var errs []*templateError
+	addError := func(message string, keys ...string) {
+		line, column := t.keyPosition(keys...)
+		errs = append(errs, &templateError{
+			Path:    t.Path,
+			Stage:   templateErrorStageValidation,
+			Message: message,
+			Line:    line,
+			Column:  column,
+		})
+	}
+
+	// Fail if no Resources attribute defined in template file
+	if t.Resources == nil {
+		addError("Template format error: At least one Resources member must be defined")
+	}
+
+	for _, k := range sortedKeys(t.Resources) {
+		data, ok := t.Resources[k].(map[string]interface{})
+		if !ok {
+			addError(fmt.Sprintf("Template format error: Every Resources object must be an object. Resource: %s", k), "Resources", k)
+			continue
+		}
+
+		// Fail if Resources map has missing Type defined
+		if data["Type"] == nil {
+			addError(fmt.Sprintf("Template format error: Every Resources object must contain a Type member. Resource: %s", k), "Resources", k)
+		} else if _, ok := data["Type"].(string); !ok {
+			addError(fmt.Sprintf("Template format error: Every Resources object must contain a Type member with a string value. Resource: %s", k), "Resources", k, "Type")
+		}
+
+		// Fail if Properties defined with no value, or null
+		if properties, isPresent := data["Properties"]; isPresent && properties == nil {
+			addError(fmt.Sprintf("[/Resources/%s/Properties] 'null' values are not allowed in templates", k), "Resources", k, "Properties")
+		}
+	}
+
+	for _, k := range sortedKeys(t.Parameters) {
+		// Fail if Parameters map has missing Type defined
+		data, ok := t.Parameters[k].(map[string]interface{})
+		if !ok || data["Type"] == nil {
+			addError(fmt.Sprintf("Template format error: Every Parameters object must contain a Type member with non-null value. Parameter: %s", k), "Parameters", k)
+		} else if _, ok := data["Type"].(string); !ok {
+			addError(fmt.Sprintf("Template format error: Every Parameters object must contain a Type member with a string value. Parameter: %s", k), "Parameters", k, "Type")
+		}
+	}
+
+	for _, k := range sortedKeys(t.Mappings) {
+		// Fail if a mapping is not a map of top level keys to name-value pairs
+		mapping, ok := t.Mappings[k].(map[string]interface{})
+		if !ok {
+			addError(fmt.Sprintf("Template format error: Every Mappings member %s must be a map", k), "Mappings", k)
+			continue
+		}
+		for _, key := range sortedKeys(mapping) {
+			if _, ok := mapping[key].(map[string]interface{}); !ok {
+				addError(fmt.Sprintf("Template format error: Every Mappings attribute must be a map. Mapping: %s, key: %s", k, key), "Mappings", k, key)
+			}
+		}
+	}
+
+	for _, k := range sortedKeys(t.Conditions) {
+		// Fail if a condition is declared without a value
+		if t.Conditions[k] == nil {
+			addError(fmt.Sprintf("Template format error: Every Conditions member must contain a non-null value. Condition: %s", k), "Conditions", k)
+		}
+	}
+
+	for _, k := range sortedKeys(t.Outputs) {
+		// Fail if Outputs map has missing Value defined
+		data, ok := t.Outputs[k].(map[string]interface{})
+		if !ok || data["Value"] == nil {
+			addError(fmt.Sprintf("Template format error: Every Outputs member must contain a Value object with non-null value. Output: %s", k), "Outputs", k)
+		}
+	}
+
+	return errs
+}
+
+// sortedKeys returns the keys of the map in sorted order, so that templates
+// are processed in a deterministic order
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
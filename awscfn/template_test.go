@@ -0,0 +1,123 @@
+package awscfn
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// templateTableRows returns the number of rows each table streams for the
+// parsed template, using the same row builders as the list functions. The
+// list functions rely on validate for the shape of their section, so a
+// template that is accepted but has an unexpected shape panics here.
+var templateTableRows = map[string]func(*cfnTemplate) int{
+	"awscfn_resource": func(template *cfnTemplate) int {
+		return len(template.resourceRows(&evaluationValues{}, true))
+	},
+	"awscfn_parameter": func(template *cfnTemplate) int {
+		return len(template.parameterRows())
+	},
+	"awscfn_output": func(template *cfnTemplate) int {
+		return len(template.outputRows(&evaluationValues{}))
+	},
+	"awscfn_mapping": func(template *cfnTemplate) int {
+		return len(template.mappingRows())
+	},
+}
+
+// tableAccepts returns an error if the table rejects the template, i.e. if
+// getTemplate returns an error or the list function would fail on it
+func tableAccepts(table string, path string) (err error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	template, errs := parseTemplateContent(path, content)
+	if len(errs) > 0 {
+		return errs[0]
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%s panicked: %v", table, r)
+		}
+	}()
+	templateTableRows[table](template)
+	return nil
+}
+
+func TestTablesAcceptSameTemplates(t *testing.T) {
+	for _, expectValid := range []bool{true, false} {
+		dir := filepath.Join("testdata", "templates", "invalid")
+		if expectValid {
+			dir = filepath.Join("testdata", "templates", "valid")
+		}
+		paths, err := filepath.Glob(filepath.Join(dir, "*"))
+		if err != nil || len(paths) == 0 {
+			t.Fatalf("no fixtures in %s: %v", dir, err)
+		}
+
+		for _, path := range paths {
+			t.Run(path, func(t *testing.T) {
+				accepted := map[string]error{}
+				for table := range templateTableRows {
+					accepted[table] = tableAccepts(table, path)
+				}
+				for table, err := range accepted {
+					if expectValid && err != nil {
+						t.Errorf("%s rejects a valid template: %v", table, err)
+					}
+					if !expectValid && err == nil {
+						t.Errorf("%s accepts an invalid template", table)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestInvalidTemplateErrors(t *testing.T) {
+	tests := []struct {
+		file  string
+		stage string
+		line  int
+	}{
+		{"empty.yaml", templateErrorStageValidation, 0},
+		// yaml.v3 reports an indentation tab on the line before it
+		{"syntax_error.yaml", templateErrorStageYAML, 3},
+		{"no_resources.yaml", templateErrorStageValidation, 0},
+		{"resources_list.yaml", templateErrorStageValidation, 1},
+		{"resource_not_object.yaml", templateErrorStageValidation, 2},
+		{"resource_missing_type.yaml", templateErrorStageValidation, 2},
+		{"resource_type_not_string.yaml", templateErrorStageValidation, 3},
+		{"resource_null_properties.yaml", templateErrorStageValidation, 4},
+		{"parameter_missing_type.yaml", templateErrorStageValidation, 2},
+		{"parameter_not_object.yaml", templateErrorStageValidation, 2},
+		{"mapping_not_map.yaml", templateErrorStageValidation, 2},
+		{"mapping_key_not_map.yaml", templateErrorStageValidation, 3},
+		{"condition_null.yaml", templateErrorStageValidation, 2},
+		{"output_missing_value.yaml", templateErrorStageValidation, 5},
+		{"output_not_object.yaml", templateErrorStageValidation, 5},
+		{"invalid_getatt.yaml", templateErrorStageYAML, 6},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			path := filepath.Join("testdata", "templates", "invalid", tt.file)
+			content, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, errs := parseTemplateContent(path, content)
+			if len(errs) == 0 {
+				t.Fatal("parseTemplateContent() returned no errors")
+			}
+			if errs[0].Stage != tt.stage {
+				t.Errorf("stage = %q, want %q (%s)", errs[0].Stage, tt.stage, errs[0].Message)
+			}
+			if errs[0].Line != tt.line {
+				t.Errorf("line = %d, want %d (%s)", errs[0].Line, tt.line, errs[0].Message)
+			}
+		})
+	}
+}
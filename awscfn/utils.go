@@ -1,7 +1,6 @@
 package awscfn
 
 import (
-	"bytes"
 	"context"
 	"errors"
 	"fmt"
@@ -30,6 +29,10 @@ func listFilesByPath(ctx context.Context, d *plugin.QueryData) ([]string, error)
 		matches = append(matches, files...)
 	}
 
+	// Paths such as "*.yaml" commonly match files that are not templates,
+	// e.g. docker-compose.yml or GitHub workflow files, which can be skipped
+	skipNonTemplates := awscfnConfig.SkipNonTemplates != nil && *awscfnConfig.SkipNonTemplates
+
 	// Sanitize the matches to ignore the directories
 	var fileList []string
 	for _, i := range matches {
@@ -38,6 +41,12 @@ func listFilesByPath(ctx context.Context, d *plugin.QueryData) ([]string, error)
 		if filehelpers.DirectoryExists(i) {
 			continue
 		}
+
+		// Ignore files that are not CloudFormation templates
+		if skipNonTemplates && !loadTemplate(ctx, d, i).template.isTemplate() {
+			plugin.Logger(ctx).Debug("listFilesByPath", "skipping non-template file", i)
+			continue
+		}
 		fileList = append(fileList, i)
 	}
 	return fileList, nil
@@ -48,7 +57,8 @@ func convert(i interface{}) interface{} {
 	case map[interface{}]interface{}:
 		data := map[string]interface{}{}
 		for k, v := range valueType {
-			data[k.(string)] = convert(v)
+			// Keys may be numbers or booleans in YAML, e.g. mapping keys
+			data[fmt.Sprintf("%v", k)] = convert(v)
 		}
 		return data
 	case []interface{}:
@@ -108,120 +118,37 @@ func treeToList(tree *yaml.Node, prefix []string, rows *Rows, searchObjectName s
 	}
 }
 
-type Fragment struct {
-	content *yaml.Node
-}
-
-func (f *Fragment) UnmarshalYAML(value *yaml.Node) error {
-	var err error
-	f.content, err = resolveCustomTags(value)
-	return err
-}
-
-type IncludeProcessor struct {
-	target interface{}
+// shortFormFunctions maps the YAML short form tag of each intrinsic function
+// to its full function name
+var shortFormFunctions = map[string]string{
+	"!And":          "Fn::And",
+	"!Base64":       "Fn::Base64",
+	"!Cidr":         "Fn::Cidr",
+	"!Condition":    "Condition",
+	"!Equals":       "Fn::Equals",
+	"!FindInMap":    "Fn::FindInMap",
+	"!GetAtt":       "Fn::GetAtt",
+	"!GetAZs":       "Fn::GetAZs",
+	"!If":           "Fn::If",
+	"!ImportValue":  "Fn::ImportValue",
+	"!Join":         "Fn::Join",
+	"!Length":       "Fn::Length",
+	"!Not":          "Fn::Not",
+	"!Or":           "Fn::Or",
+	"!Ref":          "Ref",
+	"!Select":       "Fn::Select",
+	"!Split":        "Fn::Split",
+	"!Sub":          "Fn::Sub",
+	"!ToJsonString": "Fn::ToJsonString",
+	"!Transform":    "Fn::Transform",
 }
 
-func (i *IncludeProcessor) UnmarshalYAML(value *yaml.Node) error {
-	resolved, err := resolveCustomTags(value)
-	if err != nil {
-		return err
-	}
-	return resolved.Decode(i.target)
-}
-
-// resolveCustomTags preserves YAML short tags while parsing
+// resolveCustomTags replaces YAML short form intrinsic functions with their
+// full form, e.g. "!Sub [..]" with "Fn::Sub: [..]", for scalar, sequence and
+// mapping nodes. Nodes are rewritten in place and keep their original line
+// and column, so the tree can still be used to report line numbers.
 func resolveCustomTags(node *yaml.Node) (*yaml.Node, error) {
-	switch node.Tag {
-	case "!Base64":
-		if node.Kind != yaml.ScalarNode {
-			break
-		}
-		var f Fragment
-		err := yaml.Unmarshal([]byte(fmt.Sprintf("Fn::Base64: %v", node.Value)), &f)
-		return f.content, err
-	case "!Cidr":
-		if node.Kind != yaml.ScalarNode {
-			break
-		}
-		var f Fragment
-		err := yaml.Unmarshal([]byte(fmt.Sprintf("Fn::Cidr: %v", node.Value)), &f)
-		return f.content, err
-	case "!GetAtt":
-		if node.Kind != yaml.ScalarNode {
-			break
-		}
-		var f Fragment
-		if strings.Contains(node.Value, ".") {
-			node.Value = fmt.Sprintf("%s", strings.Split(node.Value, "."))
-		}
-		err := yaml.Unmarshal([]byte(fmt.Sprintf("Fn::GetAtt: %v", node.Value)), &f)
-		return f.content, err
-	case "!GetAZs":
-		if node.Kind != yaml.ScalarNode {
-			break
-		}
-		var f Fragment
-		err := yaml.Unmarshal([]byte(fmt.Sprintf("Fn::GetAZs: %v", node.Value)), &f)
-		return f.content, err
-	case "!ImportValue":
-		if node.Kind != yaml.ScalarNode {
-			break
-		}
-		var f Fragment
-		err := yaml.Unmarshal([]byte(fmt.Sprintf("Fn::ImportValue:: %v", node.Value)), &f)
-		return f.content, err
-	case "!Join":
-		if node.Kind != yaml.ScalarNode {
-			break
-		}
-		var f Fragment
-		err := yaml.Unmarshal([]byte(fmt.Sprintf("Fn::Join: %v", node.Value)), &f)
-		return f.content, err
-	case "!Select":
-		if node.Kind != yaml.ScalarNode {
-			break
-		}
-		var f Fragment
-		err := yaml.Unmarshal([]byte(fmt.Sprintf("Fn::Select: %v", node.Value)), &f)
-		return f.content, err
-	case "!Split":
-		if node.Kind != yaml.ScalarNode {
-			break
-		}
-		var f Fragment
-		err := yaml.Unmarshal([]byte(fmt.Sprintf("Fn::Split: %v", node.Value)), &f)
-		return f.content, err
-	case "!Sub":
-		if node.Kind != yaml.ScalarNode {
-			break
-		}
-		var f Fragment
-		err := yaml.Unmarshal([]byte(fmt.Sprintf("Fn::Sub: %v", node.Value)), &f)
-		return f.content, err
-	case "!Transform":
-		if node.Kind != yaml.ScalarNode {
-			break
-		}
-		var f Fragment
-		err := yaml.Unmarshal([]byte(fmt.Sprintf("Fn::Transform: %v", node.Value)), &f)
-		return f.content, err
-	case "!Ref":
-		if node.Kind != yaml.ScalarNode {
-			break
-		}
-		var f Fragment
-		err := yaml.Unmarshal([]byte(fmt.Sprintf("Ref: %v", node.Value)), &f)
-		return f.content, err
-	case "!Condition":
-		if node.Kind != yaml.ScalarNode {
-			break
-		}
-		var f Fragment
-		err := yaml.Unmarshal([]byte(fmt.Sprintf("Condition: %v", node.Value)), &f)
-		return f.content, err
-	}
-	if node.Kind == yaml.SequenceNode || node.Kind == yaml.MappingNode {
+	if node.Kind == yaml.DocumentNode || node.Kind == yaml.SequenceNode || node.Kind == yaml.MappingNode {
 		var err error
 		for i := range node.Content {
 			node.Content[i], err = resolveCustomTags(node.Content[i])
@@ -230,12 +157,51 @@ func resolveCustomTags(node *yaml.Node) (*yaml.Node, error) {
 			}
 		}
 	}
-	return node, nil
-}
 
-func formatFileContent(content []byte) []byte {
-	content = bytes.ReplaceAll(content, []byte("!If"), []byte(fmt.Sprintf("\n%sFn::If:", strings.Repeat(" ", 8))))
-	content = bytes.ReplaceAll(content, []byte("!Equals"), []byte(fmt.Sprintf("\n%sFn::Equals:", strings.Repeat(" ", 8))))
-	content = bytes.ReplaceAll(content, []byte("!FindInMap"), []byte(fmt.Sprintf("\n%sFn::FindInMap:", strings.Repeat(" ", 8))))
-	return content
+	function, ok := shortFormFunctions[node.Tag]
+	if !ok {
+		return node, nil
+	}
+
+	switch node.Kind {
+	case yaml.ScalarNode:
+		node.Tag = "!!str"
+
+		// The short form of Fn::GetAtt takes a single string, i.e.
+		// "!GetAtt Resource.Attribute", where the attribute name may itself
+		// contain dots, e.g. "!GetAtt MyDB.Endpoint.Address"
+		if function == "Fn::GetAtt" {
+			resource, attribute, found := strings.Cut(node.Value, ".")
+			if !found {
+				return nil, fmt.Errorf("line %d: invalid !GetAtt value %q, expected Resource.Attribute", node.Line, node.Value)
+			}
+			node = &yaml.Node{
+				Kind:   yaml.SequenceNode,
+				Tag:    "!!seq",
+				Line:   node.Line,
+				Column: node.Column,
+				Content: []*yaml.Node{
+					{Kind: yaml.ScalarNode, Tag: "!!str", Value: resource, Line: node.Line, Column: node.Column},
+					{Kind: yaml.ScalarNode, Tag: "!!str", Value: attribute, Line: node.Line, Column: node.Column},
+				},
+			}
+		}
+	case yaml.SequenceNode:
+		node.Tag = "!!seq"
+	case yaml.MappingNode:
+		node.Tag = "!!map"
+	default:
+		return nil, fmt.Errorf("line %d: invalid value for %s", node.Line, node.Tag)
+	}
+
+	return &yaml.Node{
+		Kind:   yaml.MappingNode,
+		Tag:    "!!map",
+		Line:   node.Line,
+		Column: node.Column,
+		Content: []*yaml.Node{
+			{Kind: yaml.ScalarNode, Tag: "!!str", Value: function, Line: node.Line, Column: node.Column},
+			node,
+		},
+	}, nil
 }
@@ -0,0 +1,181 @@
+package awscfn
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// shortFormFixture is a template fragment whose Value uses a short form tag.
+// The tagged node is declared on line 3.
+type shortFormFixture struct {
+	name     string
+	yaml     string
+	expected string
+	// The lines of the items of a tagged sequence, which must not change
+	itemLines []int
+}
+
+func shortFormFixtures() []shortFormFixture {
+	tags := make([]string, 0, len(shortFormFunctions))
+	for tag := range shortFormFunctions {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	var fixtures []shortFormFixture
+	for _, tag := range tags {
+		function := shortFormFunctions[tag]
+
+		scalar := shortFormFixture{
+			name:     tag + " scalar",
+			yaml:     "Resources:\n  Thing:\n    Value: " + tag + " Value\n",
+			expected: `{"` + function + `":"Value"}`,
+		}
+		// The scalar form of Fn::GetAtt is split into the resource and
+		// attribute names
+		if tag == "!GetAtt" {
+			scalar.yaml = "Resources:\n  Thing:\n    Value: !GetAtt MyDB.Endpoint\n"
+			scalar.expected = `{"Fn::GetAtt":["MyDB","Endpoint"]}`
+		}
+
+		fixtures = append(fixtures,
+			scalar,
+			shortFormFixture{
+				name:     tag + " flow sequence",
+				yaml:     "Resources:\n  Thing:\n    Value: " + tag + " [a, b]\n",
+				expected: `{"` + function + `":["a","b"]}`,
+			},
+			shortFormFixture{
+				name:      tag + " block sequence",
+				yaml:      "Resources:\n  Thing:\n    Value: " + tag + "\n      - a\n      - b\n",
+				expected:  `{"` + function + `":["a","b"]}`,
+				itemLines: []int{4, 5},
+			},
+			shortFormFixture{
+				name:     tag + " mapping",
+				yaml:     "Resources:\n  Thing:\n    Value: " + tag + " {Key: Value}\n",
+				expected: `{"` + function + `":{"Key":"Value"}}`,
+			},
+		)
+	}
+
+	return append(fixtures,
+		shortFormFixture{
+			name:     "!GetAtt with a dotted attribute",
+			yaml:     "Resources:\n  Thing:\n    Value: !GetAtt A.B.C\n",
+			expected: `{"Fn::GetAtt":["A","B.C"]}`,
+		},
+		shortFormFixture{
+			name:     "nested short forms",
+			yaml:     "Resources:\n  Thing:\n    Value: !If [IsProd, !Sub '${AWS::Region}-a', !Ref AWS::NoValue]\n",
+			expected: `{"Fn::If":["IsProd",{"Fn::Sub":"${AWS::Region}-a"},{"Ref":"AWS::NoValue"}]}`,
+		},
+	)
+}
+
+// fixtureValue returns the node of the Value of the fixture, i.e. the value of
+// the only key at each level of Resources.Thing.Value
+func fixtureValue(root *yaml.Node) *yaml.Node {
+	return root.Content[0].Content[1].Content[1].Content[1]
+}
+
+func TestResolveCustomTags(t *testing.T) {
+	fixtures := shortFormFixtures()
+
+	// Every short form tag must be covered by the fixtures
+	for tag := range shortFormFunctions {
+		var found bool
+		for _, f := range fixtures {
+			if strings.HasPrefix(f.name, tag+" ") {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("no fixture for %s", tag)
+		}
+	}
+
+	for _, f := range fixtures {
+		t.Run(f.name, func(t *testing.T) {
+			var root yaml.Node
+			if err := yaml.Unmarshal([]byte(f.yaml), &root); err != nil {
+				t.Fatalf("invalid fixture: %v", err)
+			}
+			tagged := fixtureValue(&root)
+			line, column := tagged.Line, tagged.Column
+
+			if _, err := resolveCustomTags(&root); err != nil {
+				t.Fatalf("resolveCustomTags() error = %v", err)
+			}
+
+			value := fixtureValue(&root)
+			var decoded interface{}
+			if err := value.Decode(&decoded); err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			b, err := json.Marshal(convert(decoded))
+			if err != nil {
+				t.Fatalf("Marshal() error = %v", err)
+			}
+			if string(b) != f.expected {
+				t.Errorf("long form = %s, want %s", b, f.expected)
+			}
+
+			// The function and its arguments keep the position of the tag
+			if value.Line != line || value.Column != column {
+				t.Errorf("function position = %d:%d, want %d:%d", value.Line, value.Column, line, column)
+			}
+			if key := value.Content[0]; key.Line != line || key.Column != column {
+				t.Errorf("function name position = %d:%d, want %d:%d", key.Line, key.Column, line, column)
+			}
+			if args := value.Content[1]; args.Line != line {
+				t.Errorf("arguments line = %d, want %d", args.Line, line)
+			}
+			for i, itemLine := range f.itemLines {
+				if item := value.Content[1].Content[i]; item.Line != itemLine {
+					t.Errorf("item %d line = %d, want %d", i, item.Line, itemLine)
+				}
+			}
+		})
+	}
+}
+
+func TestResolveCustomTagsErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		yaml  string
+		error string
+	}{
+		{
+			name:  "!GetAtt without an attribute",
+			yaml:  "Resources:\n  Thing:\n    Value: !GetAtt A\n",
+			error: `line 3: invalid !GetAtt value "A", expected Resource.Attribute`,
+		},
+		{
+			name:  "!GetAtt without an attribute in a sequence",
+			yaml:  "Resources:\n  Thing:\n    Value:\n      - !GetAtt Bucket\n",
+			error: `line 4: invalid !GetAtt value "Bucket", expected Resource.Attribute`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var root yaml.Node
+			if err := yaml.Unmarshal([]byte(tt.yaml), &root); err != nil {
+				t.Fatalf("invalid fixture: %v", err)
+			}
+			_, err := resolveCustomTags(&root)
+			if err == nil {
+				t.Fatalf("resolveCustomTags() error = nil, want %q", tt.error)
+			}
+			if err.Error() != tt.error {
+				t.Errorf("resolveCustomTags() error = %q, want %q", err.Error(), tt.error)
+			}
+		})
+	}
+}